@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scope - what the holder of a token is allowed to do
+type Scope int
+
+const (
+	// only GET requests
+	ReadOnly Scope = iota + 1
+	// all requests, including the ones changing the board
+	ReadWrite
+)
+
+// Token - the client an access token is given to and its scope
+type Token struct {
+	Client string
+	Scope  Scope
+}
+
+// Requires a bearer token for every request.
+// Can be given many times - once for each token
+func WithToken(token string, client string, scope Scope) Option {
+	return func(handler *GameOfLifeHandler) {
+		if handler.tokens == nil {
+			handler.tokens = make(map[string]Token)
+		}
+		handler.tokens[token] = Token{Client: client, Scope: scope}
+	}
+}
+
+// Limits the requests changing the board with a token bucket per client.
+// Each client can make burst requests at once and gets perSecond more every second
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(handler *GameOfLifeHandler) {
+		handler.limiter = newRateLimiter(perSecond, burst, time.Now)
+	}
+}
+
+// The key of the client name in the request context
+type clientKey struct{}
+
+// Returns the name of the client making the request -
+// the client of its token or the remote host if there are no tokens
+func clientName(r *http.Request) string {
+	if client, ok := r.Context().Value(clientKey{}).(string); ok {
+		return client
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Wraps a request handler with the access checks.
+// Answers 401 without a valid token, 403 if the token has a smaller scope
+// and 429 if a limited request is made too often
+func (h *GameOfLifeHandler) guard(scope Scope, limited bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.tokens != nil {
+			token, ok := h.tokens[bearerToken(r)]
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="game of life"`)
+				http.Error(w, "A valid bearer token is required", http.StatusUnauthorized)
+				return
+			}
+			if token.Scope < scope {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="game of life", error="insufficient_scope"`)
+				http.Error(w, "The token does not allow changing the board", http.StatusForbidden)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), clientKey{}, token.Client))
+		}
+
+		if limited && h.limiter != nil {
+			if wait := h.limiter.take(clientName(r)); wait > 0 {
+				seconds := int(math.Ceil(wait.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		next(w, r)
+	}
+}
+
+// Returns the token from the Authorization header, or "" if there is none
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(auth[7:])
+}
+
+// Token buckets for every client
+type rateLimiter struct {
+	perSecond float64
+	burst     float64
+	now       func() time.Time
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+}
+
+// The tokens left for a client and when they were counted
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Above this number of clients the full buckets are forgotten
+const maxIdleBuckets = 1024
+
+// Creates a rate limiter. now is the clock - replaced in tests
+func newRateLimiter(perSecond float64, burst int, now func() time.Time) *rateLimiter {
+	return &rateLimiter{perSecond: perSecond, burst: float64(burst), now: now,
+		buckets: make(map[string]*bucket)}
+}
+
+// Takes a token from the bucket of the client.
+// Returns 0 on success or how long to wait for the next token
+func (limiter *rateLimiter) take(client string) time.Duration {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	now := limiter.now()
+	b, ok := limiter.buckets[client]
+	if !ok {
+		if len(limiter.buckets) >= maxIdleBuckets {
+			limiter.forgetFull(now)
+		}
+		b = &bucket{tokens: limiter.burst, updated: now}
+		limiter.buckets[client] = b
+	}
+	limiter.refill(b, now)
+
+	if b.tokens >= 1 {
+		b.tokens -= 1
+		return 0
+	}
+	if limiter.perSecond <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration((1 - b.tokens) / limiter.perSecond * float64(time.Second))
+}
+
+// Adds the tokens earned since the last update of the bucket
+func (limiter *rateLimiter) refill(b *bucket, now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(limiter.burst, b.tokens+elapsed*limiter.perSecond)
+		b.updated = now
+	}
+}
+
+// Removes the buckets which are full again - they are the same as new ones
+func (limiter *rateLimiter) forgetFull(now time.Time) {
+	for client, b := range limiter.buckets {
+		limiter.refill(b, now)
+		if b.tokens >= limiter.burst {
+			delete(limiter.buckets, client)
+		}
+	}
+}
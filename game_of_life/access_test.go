@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAccessTokens(t *testing.T) {
+	gofh := NewGameOfLifeHandler(blinker,
+		WithToken("reader-secret", "reader", ReadOnly),
+		WithToken("writer-secret", "writer", ReadWrite))
+	testSrv := httptest.NewServer(gofh)
+	defer testSrv.Close()
+
+	testTable := []struct {
+		method, path, token string
+		status              int
+	}{
+		{method: "GET", path: "/generation/", token: "", status: http.StatusUnauthorized},
+		{method: "GET", path: "/generation/", token: "wrong", status: http.StatusUnauthorized},
+		{method: "GET", path: "/generation/", token: "reader-secret", status: http.StatusOK},
+		{method: "GET", path: "/cell/status/?x=1&y=1", token: "writer-secret", status: http.StatusOK},
+		{method: "POST", path: "/generation/evolve/", token: "", status: http.StatusUnauthorized},
+		{method: "POST", path: "/generation/evolve/", token: "reader-secret", status: http.StatusForbidden},
+		{method: "POST", path: "/reset/", token: "reader-secret", status: http.StatusForbidden},
+		{method: "POST", path: "/generation/evolve/", token: "writer-secret", status: http.StatusNoContent},
+		{method: "POST", path: "/reset/", token: "writer-secret", status: http.StatusNoContent},
+	}
+
+	for _, testCase := range testTable {
+		resp, _ := doWithToken(t, testCase.method, buildUrl(testSrv.URL, testCase.path), testCase.token, "")
+		if resp.StatusCode != testCase.status {
+			t.Errorf("Expected status %d for %s %s with token %q but found %d", testCase.status,
+				testCase.method, testCase.path, testCase.token, resp.StatusCode)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("Expected a WWW-Authenticate header with status 401")
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	gofh := NewGameOfLifeHandler(blinker,
+		WithToken("first-secret", "first", ReadWrite),
+		WithToken("second-secret", "second", ReadWrite),
+		WithRateLimit(0.001, 2))
+	testSrv := httptest.NewServer(gofh)
+	defer testSrv.Close()
+
+	url := buildUrl(testSrv.URL, "/generation/evolve/")
+	for i := 0; i < 2; i++ {
+		resp, _ := doWithToken(t, "POST", url, "first-secret", "")
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("Expected status 204 for request %d but found %d", i, resp.StatusCode)
+		}
+	}
+
+	resp, _ := doWithToken(t, "POST", url, "first-secret", "")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 but found %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Errorf("Expected a Retry-After header with status 429")
+	}
+
+	// the other client has its own bucket
+	resp, _ = doWithToken(t, "POST", url, "second-secret", "")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status 204 for another client but found %d", resp.StatusCode)
+	}
+
+	// reading is not limited
+	resp, _ = doWithToken(t, "GET", buildUrl(testSrv.URL, "/generation/"), "first-secret", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200 for reading but found %d", resp.StatusCode)
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(2, 1, func() time.Time { return now })
+
+	if wait := limiter.take("client"); wait != 0 {
+		t.Errorf("Expected the first token to be free but wait is %s", wait)
+	}
+	if wait := limiter.take("client"); wait != 500*time.Millisecond {
+		t.Errorf("Expected to wait 500ms but wait is %s", wait)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if wait := limiter.take("client"); wait != 0 {
+		t.Errorf("Expected a new token after 500ms but wait is %s", wait)
+	}
+
+	now = now.Add(time.Hour)
+	limiter.take("client")
+	if wait := limiter.take("client"); wait == 0 {
+		t.Errorf("Expected the bucket to hold at most 1 token")
+	}
+}
+
+// Makes a request with a bearer token and returns the response with its body
+func doWithToken(t *testing.T, method, url, token, body string) (*http.Response, []byte) {
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+	respBytes, _ := ioutil.ReadAll(resp.Body)
+	return resp, respBytes
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestLocalWorldStep(t *testing.T) {
+	world := NewLocalWorld([][2]int64{{0, 1}, {1, 1}, {2, 1}})
+	if err := world.Step(); err != nil {
+		t.Fatal(err.Error())
+	}
+	generation, living, _ := world.Living()
+	if generation != 1 {
+		t.Errorf("Expected generation 1 but found %d", generation)
+	}
+	expected := [][2]int64{{1, 0}, {1, 1}, {1, 2}}
+	if !sameCells(living, expected) {
+		t.Errorf("Expected %v but found %v", expected, living)
+	}
+}
+
+func TestRenderBlocks(t *testing.T) {
+	living := [][2]int64{{0, 0}, {1, 1}, {2, 0}, {2, 1}, {5, 5}}
+	var out bytes.Buffer
+	RenderBlocks(&out, living, Viewport{Zoom: 1}, 3, 1, "\n")
+	expected := "▀▄█\n"
+	if out.String() != expected {
+		t.Errorf("Expected\n---\n%s\n---\nbut found\n---\n%s\n---\n", expected, out.String())
+	}
+
+	out.Reset()
+	RenderText(&out, living, Viewport{X: 1, Y: 0, Zoom: 2}, 2, 1)
+	expected = "O.\n"
+	if out.String() != expected {
+		t.Errorf("Expected\n---\n%s\n---\nbut found\n---\n%s\n---\n", expected, out.String())
+	}
+}
+
+func TestRemoteWorld(t *testing.T) {
+	downloads := 0
+	evolved := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/generation/", func(w http.ResponseWriter, r *http.Request) {
+		etag := `"0-0"`
+		body := `{"generation": 0, "living": [[1, 2]]}`
+		if evolved {
+			etag = `"1-0"`
+			body = `{"generation": 1, "living": []}`
+		}
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads += 1
+		w.Write([]byte(body))
+	})
+	mux.HandleFunc("/generation/evolve/", func(w http.ResponseWriter, r *http.Request) {
+		evolved = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+	testSrv := httptest.NewServer(mux)
+	defer testSrv.Close()
+
+	world := NewRemoteWorld(testSrv.URL + "/")
+	for i := 0; i < 3; i++ {
+		generation, living, err := world.Living()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if generation != 0 || !sameCells(living, [][2]int64{{1, 2}}) {
+			t.Errorf("Expected generation 0 with (1, 2) but found %d with %v", generation, living)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("Expected the unchanged board to be downloaded once but it was downloaded %d times", downloads)
+	}
+
+	if err := world.Step(); err != nil {
+		t.Fatal(err.Error())
+	}
+	generation, living, err := world.Living()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if generation != 1 || len(living) != 0 {
+		t.Errorf("Expected an empty generation 1 but found %d with %v", generation, living)
+	}
+}
+
+func TestRemoteSeed(t *testing.T) {
+	seed := "x = 3, y = 1\n3o!"
+	posted := ""
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cells/seed/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		posted = string(body)
+		w.WriteHeader(http.StatusCreated)
+	})
+	testSrv := httptest.NewServer(mux)
+	defer testSrv.Close()
+
+	dir, _ := ioutil.TempDir("", "life")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "blinker.rle")
+	ioutil.WriteFile(path, []byte(seed), 0644)
+
+	if _, err := setUpWorld(testSrv.URL, path); err != nil {
+		t.Fatal(err.Error())
+	}
+	if posted != seed {
+		t.Errorf("Expected the seed file to be sent to the server but found %q", posted)
+	}
+	if _, err := setUpWorld("", path); err == nil {
+		t.Errorf("Expected an error for a seed without a server")
+	}
+}
+
+// Checks if both lists contain the same cells regardless of their order
+func sameCells(found, expected [][2]int64) bool {
+	if len(found) != len(expected) {
+		return false
+	}
+	sorted := func(cells [][2]int64) [][2]int64 {
+		result := append([][2]int64(nil), cells...)
+		sort.Slice(result, func(i, j int) bool {
+			if result[i][0] != result[j][0] {
+				return result[i][0] < result[j][0]
+			}
+			return result[i][1] < result[j][1]
+		})
+		return result
+	}
+	a, b := sorted(found), sorted(expected)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
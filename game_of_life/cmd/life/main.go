@@ -0,0 +1,260 @@
+// Command life shows a game of life in the terminal.
+//
+// The game is either simulated in this process or read from a running
+// game of life server (-remote). Seeds are RLE or JSON files, they are sent
+// to the server which reads them, so -seed needs -remote.
+//
+// Interactive keys:
+//
+//	space       run / pause
+//	n, .        step one generation
+//	arrows/hjkl pan
+//	+, -        zoom in / out
+//	c           center on the living cells
+//	q           quit
+//
+// With -headless the first generations are printed to stdout instead.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+func main() {
+	remote := flag.String("remote", "", "URL of a game of life server to attach to")
+	seed := flag.String("seed", "", "RLE or JSON file with the starting cells")
+	headless := flag.Bool("headless", false, "print generations to stdout instead of starting the viewer")
+	generations := flag.Int("n", 10, "number of generations to print in headless mode")
+	format := flag.String("format", "text", "headless output format: text, blocks or json")
+	cols := flag.Int("width", 0, "width of the screen in characters, the terminal width by default")
+	rows := flag.Int("height", 0, "height of the screen in characters, the terminal height by default")
+	delay := flag.Duration("delay", 100*time.Millisecond, "time between generations while running")
+	flag.Parse()
+	if *delay <= 0 {
+		fmt.Fprintln(os.Stderr, "-delay must be positive")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *seed != "" && *remote == "" {
+		fmt.Fprintln(os.Stderr, "-seed needs -remote, the seed is read by the server")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	world, err := setUpWorld(*remote, *seed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *headless {
+		err = printGenerations(os.Stdout, world, *generations, *format, *cols, *rows)
+	} else {
+		err = interactive(world, *cols, *rows, *delay)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Creates the world and adds the cells from the seed file to it.
+// Only the worlds on a server can have a seed
+func setUpWorld(remote, seed string) (World, error) {
+	if remote == "" {
+		if seed != "" {
+			return nil, errors.New("the seed is read by the server, it needs a remote world")
+		}
+		return NewLocalWorld(nil), nil
+	}
+	world := NewRemoteWorld(remote)
+	if seed != "" {
+		data, err := ioutil.ReadFile(seed)
+		if err != nil {
+			return nil, err
+		}
+		if err := world.AddSeed(data); err != nil {
+			return nil, err
+		}
+	}
+	return world, nil
+}
+
+// Prints the current and the next n generations.
+// Without an explicit size the text formats show the bounding box of every generation
+func printGenerations(w io.Writer, world World, n int, format string, cols, rows int) error {
+	if format != "text" && format != "blocks" && format != "json" {
+		return fmt.Errorf("unknown format %q", format)
+	}
+	encoder := json.NewEncoder(w)
+	for i := 0; i <= n; i++ {
+		if i > 0 {
+			if err := world.Step(); err != nil {
+				return err
+			}
+		}
+		generation, living, err := world.Living()
+		if err != nil {
+			return err
+		}
+
+		if format == "json" {
+			if err := encoder.Encode(generationResponse{Generation: generation, Living: living}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fmt.Fprintf(w, "generation %d\n", generation)
+		view, width, height := fitView(living, cols, rows)
+		if format == "blocks" {
+			RenderBlocks(w, living, view, width, (height+1)/2, "\n")
+		} else {
+			RenderText(w, living, view, width, height)
+		}
+	}
+	return nil
+}
+
+// The biggest size of the screen in headless mode when it is not given explicitly.
+// Bigger patterns are zoomed out to fit
+const maxFit = 200
+
+// Returns a viewport showing the bounding box of the living cells
+// and its size in pixels. A non zero cols or rows fixes that size
+func fitView(living [][2]int64, cols, rows int) (Viewport, int, int) {
+	view := Viewport{Zoom: 1}
+	if len(living) == 0 {
+		return view, cols, rows
+	}
+	minX, minY, maxX, maxY := boundingBox(living)
+	view.X, view.Y = minX, minY
+
+	spanX := uint64(maxX-minX) + 1
+	spanY := uint64(maxY-minY) + 1
+	zoom := uint64(1)
+	for _, span := range []uint64{spanX, spanY} {
+		if needed := (span + maxFit - 1) / maxFit; span > maxFit && needed > zoom {
+			zoom = needed
+		}
+	}
+	if zoom > maxZoom {
+		zoom = maxZoom
+	}
+	view.Zoom = int64(zoom)
+
+	if cols <= 0 {
+		cols = fitSize(spanX, zoom)
+	}
+	if rows <= 0 {
+		rows = fitSize(spanY, zoom)
+	}
+	return view, cols, rows
+}
+
+// Returns how many pixels show span cells, but not more than maxFit
+func fitSize(span, zoom uint64) int {
+	size := (span + zoom - 1) / zoom
+	if size > maxFit || size == 0 {
+		return maxFit
+	}
+	return int(size)
+}
+
+// The interactive viewer - renders the world and reacts to keys until q is pressed
+func interactive(world World, cols, rows int, delay time.Duration) error {
+	if cols <= 0 || rows <= 0 {
+		termCols, termRows, err := terminalSize()
+		if err != nil {
+			termCols, termRows = 80, 24
+		}
+		if cols <= 0 {
+			cols = termCols
+		}
+		if rows <= 0 {
+			rows = termRows
+		}
+	}
+	// the last line is for the status
+	rows -= 1
+
+	restore, err := rawMode()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	fmt.Print("\x1b[?25l\x1b[2J")
+	defer fmt.Print("\x1b[?25h\x1b[2J\x1b[H")
+
+	keys := make(chan key)
+	go readKeys(os.Stdin, keys)
+
+	view := Viewport{Zoom: 1}
+	if _, living, err := world.Living(); err == nil {
+		view.Center(living, cols, 2*rows)
+	}
+
+	running := false
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		generation, living, err := world.Living()
+		if err != nil {
+			return err
+		}
+		// overwrite the previous frame line by line instead of clearing it to avoid flickering
+		fmt.Print("\x1b[H")
+		RenderBlocks(os.Stdout, living, view, cols, rows, "\x1b[K\r\n")
+		state := "paused"
+		if running {
+			state = "running"
+		}
+		fmt.Printf("\x1b[%d;1Hgeneration %d, %d alive, (%d, %d) zoom %d, %s\x1b[K",
+			rows+1, generation, len(living), view.X, view.Y, view.Zoom, state)
+
+		select {
+		case <-ticker.C:
+			if running {
+				if err := world.Step(); err != nil {
+					return err
+				}
+			}
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			switch {
+			case k.r == 'q':
+				return nil
+			case k.r == ' ':
+				running = !running
+			case k.r == 'n' || k.r == '.':
+				if err := world.Step(); err != nil {
+					return err
+				}
+			case k.arrow == keyUp || k.r == 'k':
+				view.Pan(0, -2)
+			case k.arrow == keyDown || k.r == 'j':
+				view.Pan(0, 2)
+			case k.arrow == keyLeft || k.r == 'h':
+				view.Pan(-2, 0)
+			case k.arrow == keyRight || k.r == 'l':
+				view.Pan(2, 0)
+			case k.r == '+' || k.r == '=':
+				view.SetZoom(view.Zoom/2, cols, 2*rows)
+			case k.r == '-':
+				view.SetZoom(view.Zoom*2, cols, 2*rows)
+			case k.r == 'c':
+				view.Center(living, cols, 2*rows)
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// The biggest zoom, keeps the screen size in cells far from the int64 limits
+const maxZoom = 1 << 32
+
+// Viewport - the part of the board shown on the screen
+type Viewport struct {
+	// the cell in the top left corner of the screen
+	X, Y int64
+	// how many cells are shown by a single pixel in each direction
+	Zoom int64
+}
+
+// Moves the viewport by dx, dy screen pixels
+func (view *Viewport) Pan(dx, dy int64) {
+	view.X = saturatingAdd(view.X, dx*view.Zoom)
+	view.Y = saturatingAdd(view.Y, dy*view.Zoom)
+}
+
+// Changes the zoom while keeping the center of a width x height pixels screen in place
+func (view *Viewport) SetZoom(zoom int64, width, height int) {
+	if zoom < 1 {
+		zoom = 1
+	}
+	if zoom > maxZoom {
+		zoom = maxZoom
+	}
+	centerX := saturatingAdd(view.X, int64(width)/2*view.Zoom)
+	centerY := saturatingAdd(view.Y, int64(height)/2*view.Zoom)
+	view.Zoom = zoom
+	view.X = saturatingAdd(centerX, -int64(width)/2*zoom)
+	view.Y = saturatingAdd(centerY, -int64(height)/2*zoom)
+}
+
+// Centers the viewport on the bounding box of the living cells
+func (view *Viewport) Center(living [][2]int64, width, height int) {
+	if len(living) == 0 {
+		return
+	}
+	minX, minY, maxX, maxY := boundingBox(living)
+	// halve before adding so the sum can not overflow
+	centerX := minX/2 + maxX/2 + (minX%2+maxX%2)/2
+	centerY := minY/2 + maxY/2 + (minY%2+maxY%2)/2
+	view.X = saturatingAdd(centerX, -int64(width)/2*view.Zoom)
+	view.Y = saturatingAdd(centerY, -int64(height)/2*view.Zoom)
+}
+
+// Returns the smallest rectangle containing all the cells
+func boundingBox(cells [][2]int64) (minX, minY, maxX, maxY int64) {
+	minX, minY = math.MaxInt64, math.MaxInt64
+	maxX, maxY = math.MinInt64, math.MinInt64
+	for _, cell := range cells {
+		if cell[0] < minX {
+			minX = cell[0]
+		}
+		if cell[0] > maxX {
+			maxX = cell[0]
+		}
+		if cell[1] < minY {
+			minY = cell[1]
+		}
+		if cell[1] > maxY {
+			maxY = cell[1]
+		}
+	}
+	return
+}
+
+// Adds without wrapping around the int64 limits
+func saturatingAdd(a, b int64) int64 {
+	if b > 0 && a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	if b < 0 && a < math.MinInt64-b {
+		return math.MinInt64
+	}
+	return a + b
+}
+
+// Returns which pixels of a width x height screen are lit.
+// A pixel is lit if any of the cells it covers is alive
+func (view Viewport) pixels(living [][2]int64, width, height int) [][]bool {
+	screen := make([][]bool, height)
+	for i := range screen {
+		screen[i] = make([]bool, width)
+	}
+	for _, cell := range living {
+		// cells to the left or above the viewport are skipped before dividing,
+		// the subtraction can not overflow after that
+		if cell[0] < view.X || cell[1] < view.Y {
+			continue
+		}
+		px := uint64(cell[0]-view.X) / uint64(view.Zoom)
+		py := uint64(cell[1]-view.Y) / uint64(view.Zoom)
+		if px < uint64(width) && py < uint64(height) {
+			screen[py][px] = true
+		}
+	}
+	return screen
+}
+
+// Draws the viewport with Unicode half blocks.
+// Every character shows two pixels - one above the other,
+// so cols x rows characters show cols x 2*rows pixels
+func RenderBlocks(w io.Writer, living [][2]int64, view Viewport, cols, rows int, newline string) {
+	screen := view.pixels(living, cols, 2*rows)
+	var line strings.Builder
+	for row := 0; row < rows; row++ {
+		line.Reset()
+		top, bottom := screen[2*row], screen[2*row+1]
+		for col := 0; col < cols; col++ {
+			switch {
+			case top[col] && bottom[col]:
+				line.WriteString("█")
+			case top[col]:
+				line.WriteString("▀")
+			case bottom[col]:
+				line.WriteString("▄")
+			default:
+				line.WriteByte(' ')
+			}
+		}
+		fmt.Fprint(w, strings.TrimRight(line.String(), " "), newline)
+	}
+}
+
+// Draws the viewport with plain ASCII - 'O' for living and '.' for dead pixels
+func RenderText(w io.Writer, living [][2]int64, view Viewport, cols, rows int) {
+	screen := view.pixels(living, cols, rows)
+	for _, pixels := range screen {
+		line := make([]byte, cols)
+		for col, alive := range pixels {
+			if alive {
+				line[col] = 'O'
+			} else {
+				line[col] = '.'
+			}
+		}
+		fmt.Fprintln(w, string(line))
+	}
+}
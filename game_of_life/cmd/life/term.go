@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Keys understood by the interactive viewer
+const (
+	keyNone = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+)
+
+// A key press - either a printable rune or one of the arrow keys
+type key struct {
+	r     rune
+	arrow int
+}
+
+// Switches the terminal to raw mode with stty.
+// Returns a function which restores the previous mode
+func rawMode() (restore func(), err error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() {
+		stty(strings.TrimSpace(state))
+	}, nil
+}
+
+// Returns the size of the terminal in characters
+func terminalSize() (cols, rows int, err error) {
+	size, err := stty("size")
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := fmt.Sscan(size, &rows, &cols); err != nil {
+		return 0, 0, err
+	}
+	return cols, rows, nil
+}
+
+// Runs stty on the terminal attached to stdin
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// Reads key presses and sends them to keys until r is exhausted
+func readKeys(r io.Reader, keys chan<- key) {
+	defer close(keys)
+	reader := bufio.NewReader(r)
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return
+		}
+		if b != 0x1b {
+			keys <- key{r: rune(b)}
+			continue
+		}
+		// arrows are sent as ESC [ A-D
+		if next, err := reader.Peek(2); err == nil && next[0] == '[' {
+			reader.Discard(2)
+			switch next[1] {
+			case 'A':
+				keys <- key{arrow: keyUp}
+			case 'B':
+				keys <- key{arrow: keyDown}
+			case 'C':
+				keys <- key{arrow: keyRight}
+			case 'D':
+				keys <- key{arrow: keyLeft}
+			}
+			continue
+		}
+		keys <- key{r: rune(b)}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// World is the board the viewer looks at.
+// It is either simulated in this process or lives on a game of life server.
+type World interface {
+	// Returns the generation number and all the living cells
+	Living() (generation int, living [][2]int64, err error)
+	// Calculates the next generation
+	Step() error
+	// Makes the given cells alive
+	Add(cells [][2]int64) error
+}
+
+// LocalWorld - a game of life simulated in this process
+type LocalWorld struct {
+	generation int
+	board      map[[2]int64]bool
+}
+
+// Creates a new in-process world with the given living cells
+func NewLocalWorld(cells [][2]int64) *LocalWorld {
+	world := &LocalWorld{board: make(map[[2]int64]bool)}
+	world.Add(cells)
+	return world
+}
+
+// Returns the generation number and all the living cells
+func (world *LocalWorld) Living() (int, [][2]int64, error) {
+	living := make([][2]int64, 0, len(world.board))
+	for cell := range world.board {
+		living = append(living, cell)
+	}
+	return world.generation, living, nil
+}
+
+// Makes the given cells alive
+func (world *LocalWorld) Add(cells [][2]int64) error {
+	for _, cell := range cells {
+		world.board[cell] = true
+	}
+	return nil
+}
+
+// Calculates the next generation using the standard B3/S23 rules
+func (world *LocalWorld) Step() error {
+	neighbours := make(map[[2]int64]int)
+	for cell := range world.board {
+		forEachNeighbour(cell, func(neighbour [2]int64) {
+			neighbours[neighbour] += 1
+		})
+	}
+
+	next := make(map[[2]int64]bool)
+	for cell, count := range neighbours {
+		if count == 3 || (count == 2 && world.board[cell]) {
+			next[cell] = true
+		}
+	}
+	world.board = next
+	world.generation += 1
+	return nil
+}
+
+// Calls f for every neighbour of the cell which fits in int64
+func forEachNeighbour(cell [2]int64, f func([2]int64)) {
+	for dx := int64(-1); dx <= 1; dx++ {
+		for dy := int64(-1); dy <= 1; dy++ {
+			if dx == 0 && dy == 0 {
+				continue
+			}
+			x, okX := shift(cell[0], dx)
+			y, okY := shift(cell[1], dy)
+			if okX && okY {
+				f([2]int64{x, y})
+			}
+		}
+	}
+}
+
+// Adds a delta of -1, 0 or 1 to v. The result is not ok if it overflows
+func shift(v int64, delta int64) (int64, bool) {
+	if (delta < 0 && v == math.MinInt64) || (delta > 0 && v == math.MaxInt64) {
+		return 0, false
+	}
+	return v + delta, true
+}
+
+// RemoteWorld - a game running on a game of life server
+type RemoteWorld struct {
+	baseURL string
+	client  *http.Client
+
+	// the last /generation/ response, reused while the server answers 304
+	etag       string
+	generation generationResponse
+}
+
+// The body of the /generation/ responses
+type generationResponse struct {
+	Generation int        `json:"generation"`
+	Living     [][2]int64 `json:"living"`
+}
+
+// The points sent to /cells/
+type point struct {
+	X int64 `json:"x"`
+	Y int64 `json:"y"`
+}
+
+// Creates a world attached to the server at baseURL
+func NewRemoteWorld(baseURL string) *RemoteWorld {
+	return &RemoteWorld{baseURL: strings.TrimSuffix(baseURL, "/"), client: http.DefaultClient}
+}
+
+// Returns the generation and the living cells of the server.
+// Sends the last ETag so an unchanged board is not downloaded again
+func (world *RemoteWorld) Living() (int, [][2]int64, error) {
+	req, err := http.NewRequest("GET", world.baseURL+"/generation/", nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	if world.etag != "" {
+		req.Header.Set("If-None-Match", world.etag)
+	}
+
+	resp, err := world.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+	case http.StatusOK:
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return 0, nil, err
+		}
+		var generation generationResponse
+		if err := json.Unmarshal(body, &generation); err != nil {
+			return 0, nil, err
+		}
+		world.generation = generation
+		world.etag = resp.Header.Get("ETag")
+	default:
+		return 0, nil, responseError(resp)
+	}
+	return world.generation.Generation, world.generation.Living, nil
+}
+
+// Asks the server to calculate the next generation
+func (world *RemoteWorld) Step() error {
+	return world.post("/generation/evolve/", nil, http.StatusNoContent)
+}
+
+// Adds living cells on the server
+func (world *RemoteWorld) Add(cells [][2]int64) error {
+	points := make([]point, len(cells))
+	for i, cell := range cells {
+		points[i] = point{X: cell[0], Y: cell[1]}
+	}
+	body, err := json.Marshal(points)
+	if err != nil {
+		return err
+	}
+	return world.post("/cells/", body, http.StatusCreated)
+}
+
+// Sends the RLE or JSON seed file to the server, which adds its cells
+func (world *RemoteWorld) AddSeed(data []byte) error {
+	return world.post("/cells/seed/", data, http.StatusCreated)
+}
+
+// Posts body to path and checks the status of the response
+func (world *RemoteWorld) post(path string, body []byte, expected int) error {
+	resp, err := world.client.Post(world.baseURL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != expected {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// Builds an error from an unexpected response
+func responseError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+	text := strings.TrimSpace(string(body))
+	if text == "" {
+		return errors.New(resp.Status)
+	}
+	return fmt.Errorf("%s: %s", resp.Status, text)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Kinds of the server-sent events
+const (
+	// the whole board, sent first and after every change
+	EventGeneration = "generation"
+	// cells added or removed by a client - the operation
+	EventEdit = "edit"
+	// a session moved its cursor
+	EventCursor = "cursor"
+	// a session started or ended
+	EventJoin  = "join"
+	EventLeave = "leave"
+)
+
+// A listener of the board changes - see subscribe
+type subscriber struct {
+	// the session listening, it does not get its own messages
+	session string
+	// receives a value after the board changes.
+	// Many changes in a row may be seen as one, only the latest board matters
+	changes chan struct{}
+	// the messages of the other sessions
+	messages chan sessionMessage
+}
+
+// An event for the subscribers with its json data
+type sessionMessage struct {
+	event string
+	data  []byte
+}
+
+// The messages kept for a slow subscriber, the newer ones are dropped
+const maxPendingMessages = 64
+
+// Starts telling about the changes of the board and the messages of the sessions
+// other than session. Returns a function to stop it
+func (game *GameOfLife) subscribe(session string) (*subscriber, func()) {
+	s := &subscriber{session: session, changes: make(chan struct{}, 1),
+		messages: make(chan sessionMessage, maxPendingMessages)}
+	game.subscribersMutex.Lock()
+	if game.subscribers == nil {
+		game.subscribers = make(map[*subscriber]bool)
+	}
+	game.subscribers[s] = true
+	game.subscribersMutex.Unlock()
+
+	return s, func() {
+		game.subscribersMutex.Lock()
+		delete(game.subscribers, s)
+		game.subscribersMutex.Unlock()
+	}
+}
+
+// Tells the subscribers that the board has changed.
+// Never waits for a slow subscriber - it already has a change to read
+func (game *GameOfLife) notify() {
+	game.subscribersMutex.Lock()
+	defer game.subscribersMutex.Unlock()
+	for s := range game.subscribers {
+		select {
+		case s.changes <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Sends the message of a session to the subscribers of the other sessions.
+// A subscriber which is too slow misses it, the board it gets is still right
+func (game *GameOfLife) broadcast(from string, event string, value interface{}) {
+	data, _ := json.Marshal(value)
+	game.subscribersMutex.Lock()
+	defer game.subscribersMutex.Unlock()
+	for s := range game.subscribers {
+		if from != "" && s.session == from {
+			continue
+		}
+		select {
+		case s.messages <- sessionMessage{event: event, data: data}:
+		default:
+		}
+	}
+}
+
+// Responsible to answer to /events/?session= requests
+// Streams the board as server-sent events - the current one first
+// and then a new one after each change, until the client goes away.
+// The edits and cursors of the other sessions are streamed too.
+// The session, if given, ends with the stream
+func (game *GameOfLife) events(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	session, ok := game.requestSession(w, r)
+	if !ok {
+		return
+	}
+	sessionID := ""
+	if session != nil {
+		sessionID = session.ID
+		game.startStream(sessionID)
+		defer game.leave(sessionID)
+	}
+
+	s, unsubscribe := game.subscribe(sessionID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	changed := true
+	for {
+		if changed {
+			game.rwMutex.RLock()
+			etag := game.etag()
+			generation, _ := json.Marshal(Generation{Generation: game.generation, Living: game.getLiving(),
+				Version: game.version()})
+			game.rwMutex.RUnlock()
+
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", etag, EventGeneration, generation); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+
+		select {
+		case <-s.changes:
+			changed = true
+		case m := <-s.messages:
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", m.event, m.data); err != nil {
+				return
+			}
+			flusher.Flush()
+			changed = false
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Reasons for stopping the evolution
+const (
+	StoppedPopulationAbove = "population_above"
+	StoppedPopulationBelow = "population_below"
+	StoppedEmpty           = "empty"
+	StoppedLeftRegion      = "left_region"
+	StoppedTimeout         = "timeout"
+	StoppedMaxGenerations  = "max_generations"
+	StoppedCancelled       = "cancelled"
+)
+
+// Returned when evolving until nothing - it could go on forever
+var ErrNoConditions = errors.New("at least one stop condition is required")
+
+// Conditions - when to stop evolving.
+// The evolution stops as soon as any of the given conditions is met.
+// They are checked before every generation, so nothing evolves if one is already met
+type Conditions struct {
+	// stop when there are more living cells than this
+	PopulationAbove *int `json:"population_above,omitempty"`
+	// stop when there are less living cells than this
+	PopulationBelow *int `json:"population_below,omitempty"`
+	// stop when there are no living cells
+	Empty bool `json:"empty,omitempty"`
+	// stop when a living cell is outside of this region
+	LeavesRegion *Region `json:"leaves_region,omitempty"`
+	// stop after this much time, for example "10s"
+	Timeout Duration `json:"timeout,omitempty"`
+	// stop after evolving this many generations
+	MaxGenerations int `json:"max_generations,omitempty"`
+}
+
+// Duration - time.Duration written as "1m30s" in JSON
+type Duration time.Duration
+
+// Reads a duration from a string like "10s"
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Writes the duration as a string like "10s"
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Checks if there is anything to wait for
+func (conditions Conditions) empty() bool {
+	return conditions.PopulationAbove == nil && conditions.PopulationBelow == nil &&
+		!conditions.Empty && conditions.LeavesRegion == nil &&
+		conditions.Timeout <= 0 && conditions.MaxGenerations <= 0
+}
+
+// Type used for creating json for /generation/evolve-until/ responses
+type EvolveResult struct {
+	// the generation the evolution stopped at
+	Generation int `json:"generation"`
+	// how many generations were evolved
+	Evolved int `json:"evolved"`
+	// the number of living cells in the last generation
+	Population int `json:"population"`
+	// why the evolution stopped, one of the Stopped... constants
+	Reason string `json:"reason"`
+}
+
+// Evolves generation after generation until one of the conditions is met
+// or ctx is done. Other requests are served between the generations.
+// The error is ctx.Err() if the evolution was cancelled
+// or the error of the operation log if writing to it failed.
+func (game *GameOfLife) EvolveUntil(ctx context.Context, conditions Conditions) (EvolveResult, error) {
+	if conditions.empty() {
+		return EvolveResult{}, ErrNoConditions
+	}
+
+	var deadline <-chan time.Time
+	if conditions.Timeout > 0 {
+		timer := time.NewTimer(time.Duration(conditions.Timeout))
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	// the evolve operations are written in the log in the name of the client
+	client, _ := ctx.Value(clientKey{}).(string)
+	address, _ := ctx.Value(addressKey{}).(string)
+
+	result := EvolveResult{}
+	for {
+		game.rwMutex.RLock()
+		result.Generation = game.generation
+		result.Population = game.population()
+		result.Reason = game.stopReason(conditions, result.Population)
+		game.rwMutex.RUnlock()
+
+		if result.Reason == "" && conditions.MaxGenerations > 0 && result.Evolved >= conditions.MaxGenerations {
+			result.Reason = StoppedMaxGenerations
+		}
+		if result.Reason != "" {
+			return result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			result.Reason = StoppedCancelled
+			return result, ctx.Err()
+		case <-deadline:
+			result.Reason = StoppedTimeout
+			return result, nil
+		default:
+		}
+
+		if err := game.do(Operation{Type: OpEvolve, Client: client, Address: address}); err != nil {
+			return result, err
+		}
+		result.Evolved += 1
+	}
+}
+
+// Returns the condition which is met by the board, or "" if there is none
+// Has to be called while holding the rwMutex
+func (game *GameOfLife) stopReason(conditions Conditions, population int) string {
+	switch {
+	case conditions.PopulationAbove != nil && population > *conditions.PopulationAbove:
+		return StoppedPopulationAbove
+	case conditions.PopulationBelow != nil && population < *conditions.PopulationBelow:
+		return StoppedPopulationBelow
+	case conditions.Empty && population == 0:
+		return StoppedEmpty
+	case conditions.LeavesRegion != nil && game.leftRegion(*conditions.LeavesRegion):
+		return StoppedLeftRegion
+	}
+	return ""
+}
+
+// Returns the number of living cells
+// Has to be called while holding the rwMutex
+func (game *GameOfLife) population() int {
+	count := 0
+	for _, ym := range game.board {
+		for _, alive := range ym {
+			if alive {
+				count += 1
+			}
+		}
+	}
+	return count
+}
+
+// Checks if any living cell is outside of the region
+// Has to be called while holding the rwMutex
+func (game *GameOfLife) leftRegion(region Region) bool {
+	for x, ym := range game.board {
+		for y, alive := range ym {
+			if alive && !region.contains(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Responsible to answer to /generation/evolve-until/ requests
+// Evolves until the conditions in the body are met or the client goes away
+func (game *GameOfLife) evolveUntil(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	defer r.Body.Close()
+	bytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var conditions Conditions
+	if err := json.Unmarshal(bytes, &conditions); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), clientKey{}, clientName(r))
+	ctx = context.WithValue(ctx, addressKey{}, r.RemoteAddr)
+	result, err := game.EvolveUntil(ctx, conditions)
+	if err == ErrNoConditions {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil && err != ctx.Err() {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// a cancelled evolution is still reported - in case anyone is listening
+
+	body, _ := json.Marshal(result)
+	message(w, body, http.StatusOK)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var glider = [][2]int64{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}}
+
+var blinker = [][2]int64{{0, 1}, {1, 1}, {2, 1}}
+
+func TestEvolveUntilConditions(t *testing.T) {
+	below := 1
+	above := 4
+	testTable := []struct {
+		name       string
+		cells      [][2]int64
+		conditions Conditions
+		reason     string
+		evolved    int
+	}{
+		{name: "max generations", cells: blinker,
+			conditions: Conditions{MaxGenerations: 5},
+			reason:     StoppedMaxGenerations, evolved: 5},
+		{name: "empty", cells: [][2]int64{{0, 0}, {1, 0}},
+			conditions: Conditions{Empty: true, MaxGenerations: 10},
+			reason:     StoppedEmpty, evolved: 1},
+		{name: "already met", cells: glider,
+			conditions: Conditions{PopulationAbove: &above},
+			reason:     StoppedPopulationAbove, evolved: 0},
+		{name: "population below", cells: [][2]int64{{0, 0}, {1, 0}},
+			conditions: Conditions{PopulationBelow: &below, MaxGenerations: 10},
+			reason:     StoppedPopulationBelow, evolved: 1},
+		{name: "left region", cells: glider,
+			conditions: Conditions{LeavesRegion: &Region{MinX: 0, MinY: 0, MaxX: 3, MaxY: 3}},
+			reason:     StoppedLeftRegion, evolved: 5},
+	}
+
+	for _, testCase := range testTable {
+		game := NewGameOfLifeHandler(testCase.cells).gameOfLife
+		result, err := game.EvolveUntil(context.Background(), testCase.conditions)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", testCase.name, err)
+			continue
+		}
+		if result.Reason != testCase.reason {
+			t.Errorf("%s: expected reason %s but found %s", testCase.name, testCase.reason, result.Reason)
+		}
+		if result.Evolved != testCase.evolved {
+			t.Errorf("%s: expected %d evolved generations but found %d", testCase.name, testCase.evolved, result.Evolved)
+		}
+		if result.Generation != game.generation {
+			t.Errorf("%s: expected generation %d but found %d", testCase.name, game.generation, result.Generation)
+		}
+	}
+}
+
+func TestEvolveUntilTimeout(t *testing.T) {
+	game := NewGameOfLifeHandler(blinker).gameOfLife
+	start := time.Now()
+	result, err := game.EvolveUntil(context.Background(), Conditions{Timeout: Duration(50 * time.Millisecond)})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if result.Reason != StoppedTimeout {
+		t.Errorf("Expected reason %s but found %s", StoppedTimeout, result.Reason)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected to stop shortly after the timeout but it took %s", elapsed)
+	}
+}
+
+func TestEvolveUntilCancelled(t *testing.T) {
+	game := NewGameOfLifeHandler(blinker).gameOfLife
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	result, err := game.EvolveUntil(ctx, Conditions{Empty: true})
+	if err != context.Canceled {
+		t.Errorf("Expected error %s but found %v", context.Canceled, err)
+	}
+	if result.Reason != StoppedCancelled {
+		t.Errorf("Expected reason %s but found %s", StoppedCancelled, result.Reason)
+	}
+}
+
+func TestEvolveUntilRequest(t *testing.T) {
+	testSrv := setUpServer(blinker)
+	defer testSrv.Close()
+
+	url := buildUrl(testSrv.URL, "/generation/evolve-until/")
+
+	resp, err := http.Post(url, "application/json", bytes.NewBufferString(`{}`))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without conditions but found %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(url, "application/json", bytes.NewBufferString(`{"max_generations": 3, "timeout": "10s"}`))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 but found %d", resp.StatusCode)
+	}
+
+	respBytes, _ := ioutil.ReadAll(resp.Body)
+	result := EvolveResult{}
+	if err := json.Unmarshal(respBytes, &result); err != nil {
+		t.Fatalf("Error decoding json: %s", err)
+	}
+	expected := EvolveResult{Generation: 3, Evolved: 3, Population: 3, Reason: StoppedMaxGenerations}
+	if result != expected {
+		t.Errorf("Expected %+v but found %+v", expected, result)
+	}
+}
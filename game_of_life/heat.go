@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// How much of the heat of a cell is left after each generation
+const heatDecay = 0.9
+
+// The heat of a cell is forgotten below this
+const minHeat = 0.01
+
+// Returns the generation in which a living cell was born.
+// Cells added before their birth was tracked are taken as born now
+// Has to be called while holding the rwMutex
+func (game *GameOfLife) bornAt(cell [2]int64) int {
+	if generation, ok := game.born[cell]; ok {
+		return generation
+	}
+	return game.generation
+}
+
+// Returns for how many generations the cell has been alive, 0 for dead cells
+// Has to be called while holding the rwMutex
+func (game *GameOfLife) age(x int64, y int64) int {
+	if !game.isAlive(x, y) {
+		return 0
+	}
+	return game.generation - game.bornAt([2]int64{x, y})
+}
+
+// Updates the ages and the heat before the board is replaced with the next generation.
+// Every cell which is born or dies gets one more heat, the old heat decays
+// Has to be called while holding the rwMutex for writing
+func (game *GameOfLife) trackGeneration(newBoard map[int64]map[int64]bool) {
+	if game.heat == nil {
+		game.heat = make(map[[2]int64]float64)
+	}
+	for cell, heat := range game.heat {
+		if heat *= heatDecay; heat < minHeat {
+			delete(game.heat, cell)
+		} else {
+			game.heat[cell] = heat
+		}
+	}
+
+	born := make(map[[2]int64]int)
+	for x, ym := range newBoard {
+		for y, alive := range ym {
+			if !alive {
+				continue
+			}
+			cell := [2]int64{x, y}
+			if game.isAlive(x, y) {
+				born[cell] = game.bornAt(cell)
+			} else {
+				born[cell] = game.generation + 1
+				game.heat[cell] += 1
+			}
+		}
+	}
+	for x, ym := range game.board {
+		for y, alive := range ym {
+			if alive && !newBoard[x][y] {
+				game.heat[[2]int64{x, y}] += 1
+			}
+		}
+	}
+	game.born = born
+}
+
+// Updates the ages of the edited cells - the added cells are born now
+// and the removed ones are forgotten. Edits do not change the heat
+// Has to be called while holding the rwMutex for writing
+func (game *GameOfLife) trackEdit(cells [][2]int64) {
+	if game.born == nil {
+		game.born = make(map[[2]int64]int)
+	}
+	for _, cell := range cells {
+		if game.isAlive(cell[0], cell[1]) {
+			game.born[cell] = game.bornAt(cell)
+		} else {
+			delete(game.born, cell)
+		}
+	}
+}
+
+// HeatCell - the heat of a cell, or of a bin starting at the cell
+type HeatCell struct {
+	X    int64   `json:"x"`
+	Y    int64   `json:"y"`
+	Heat float64 `json:"heat"`
+}
+
+// Type used for creating json for /heatmap/ responses
+type HeatMap struct {
+	Generation int        `json:"generation"`
+	Bin        int64      `json:"bin"`
+	Cells      []HeatCell `json:"cells"`
+}
+
+// Returns the heat in the region summed in square bins with the given side.
+// Each bin is named by its top left cell. Cells without heat are left out
+func (game *GameOfLife) HeatMap(region *Region, bin int64) HeatMap {
+	game.rwMutex.RLock()
+	defer game.rwMutex.RUnlock()
+
+	bins := make(map[[2]int64]float64)
+	for cell, heat := range game.heat {
+		if region == nil || region.contains(cell[0], cell[1]) {
+			bins[[2]int64{binStart(cell[0], bin), binStart(cell[1], bin)}] += heat
+		}
+	}
+
+	heatMap := HeatMap{Generation: game.generation, Bin: bin, Cells: make([]HeatCell, 0, len(bins))}
+	for cell, heat := range bins {
+		heatMap.Cells = append(heatMap.Cells, HeatCell{X: cell[0], Y: cell[1], Heat: heat})
+	}
+	sort.Slice(heatMap.Cells, func(i, j int) bool {
+		if heatMap.Cells[i].X != heatMap.Cells[j].X {
+			return heatMap.Cells[i].X < heatMap.Cells[j].X
+		}
+		return heatMap.Cells[i].Y < heatMap.Cells[j].Y
+	})
+	return heatMap
+}
+
+// Returns the first coordinate of the bin containing c. Bins start at multiples of bin
+func binStart(c int64, bin int64) int64 {
+	mod := c % bin
+	if mod < 0 {
+		mod += bin
+	}
+	start, ok := addInt64(c, -mod)
+	if !ok {
+		// the bin goes below the board, it starts at its edge
+		return math.MinInt64
+	}
+	return start
+}
+
+// Reads the region from the min_x, min_y, max_x and max_y query parameters.
+// They are either all given or none of them - then the region is nil
+func readRegion(query url.Values) (*Region, error) {
+	names := []string{"min_x", "min_y", "max_x", "max_y"}
+	values := make([]int64, len(names))
+	given := 0
+	for i, name := range names {
+		if query.Get(name) == "" {
+			continue
+		}
+		value, err := strconv.ParseInt(query.Get(name), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+		given += 1
+	}
+	if given == 0 {
+		return nil, nil
+	}
+	if given != len(names) {
+		return nil, errors.New("min_x, min_y, max_x and max_y must be given together")
+	}
+	region := &Region{MinX: values[0], MinY: values[1], MaxX: values[2], MaxY: values[3]}
+	if region.MinX > region.MaxX || region.MinY > region.MaxY {
+		return nil, errors.New("region min must not be bigger than max")
+	}
+	return region, nil
+}
+
+// Responsible to answer to /heatmap/?min_x=&min_y=&max_x=&max_y=&bin= requests
+// The region is the whole board if it is not given, bin is 1 if it is not given
+func (game *GameOfLife) getHeatMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	region, err := readRegion(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var bin int64 = 1
+	if binStr := r.URL.Query().Get("bin"); binStr != "" {
+		if bin, err = strconv.ParseInt(binStr, 10, 64); err != nil || bin < 1 {
+			http.Error(w, "bin must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	heatMap, _ := json.Marshal(game.HeatMap(region, bin))
+	message(w, heatMap, http.StatusOK)
+}
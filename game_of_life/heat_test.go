@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCellAge(t *testing.T) {
+	block := [][2]int64{{0, 0}, {0, 1}, {1, 0}, {1, 1}}
+	gofh := NewGameOfLifeHandler(append(block, [2]int64{10, 1}, [2]int64{11, 1}, [2]int64{12, 1}))
+	game := gofh.gameOfLife
+	for i := 0; i < 2; i++ {
+		game.do(Operation{Type: OpEvolve})
+	}
+	game.do(Operation{Type: OpAddCells, Cells: [][2]int64{{-5, -5}}})
+
+	testSrv := httptest.NewServer(gofh)
+	defer testSrv.Close()
+
+	testTable := []struct {
+		x, y  int64
+		alive bool
+		age   int
+	}{
+		// the block is there from the start
+		{x: 0, y: 0, alive: true, age: 2},
+		// the middle of the blinker never dies
+		{x: 11, y: 1, alive: true, age: 2},
+		// the ends of the blinker are born again in the second generation
+		{x: 10, y: 1, alive: true, age: 0},
+		{x: 11, y: 0, alive: false, age: 0},
+		// added after the evolution
+		{x: -5, y: -5, alive: true, age: 0},
+	}
+	for _, testCase := range testTable {
+		resp, err := http.Get(buildUrl(testSrv.URL, fmt.Sprintf("/cell/status/?x=%d&y=%d", testCase.x, testCase.y)))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		status := Alive{}
+		err = json.NewDecoder(resp.Body).Decode(&status)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Error decoding json: %s", err)
+		}
+		if status.Alive != testCase.alive || status.Age != testCase.age {
+			t.Errorf("Expected alive %t with age %d for (%d, %d) but found %+v",
+				testCase.alive, testCase.age, testCase.x, testCase.y, status)
+		}
+	}
+
+	game.do(Operation{Type: OpReset})
+	game.do(Operation{Type: OpAddCells, Cells: [][2]int64{{0, 0}}})
+	game.rwMutex.RLock()
+	if age := game.age(0, 0); age != 0 {
+		t.Errorf("Expected age 0 after reset but found %d", age)
+	}
+	game.rwMutex.RUnlock()
+}
+
+func TestHeatMap(t *testing.T) {
+	// a blinker far from a block - only the blinker is active
+	cells := [][2]int64{{100, 101}, {101, 101}, {102, 101}, {0, 0}, {0, 1}, {1, 0}, {1, 1}}
+	game := NewGameOfLifeHandler(cells).gameOfLife
+	game.do(Operation{Type: OpEvolve})
+	game.do(Operation{Type: OpEvolve})
+
+	heatMap := game.HeatMap(nil, 1)
+	// the four cells around the middle of the blinker changed twice
+	if len(heatMap.Cells) != 4 {
+		t.Fatalf("Expected 4 hot cells but found %v", heatMap.Cells)
+	}
+	for _, cell := range heatMap.Cells {
+		if cell.X < 100 || cell.X > 102 || cell.Y < 100 || cell.Y > 102 {
+			t.Errorf("Expected only the blinker to be hot but found %+v", cell)
+		}
+		if math.Abs(cell.Heat-1.9) > 1e-9 {
+			t.Errorf("Expected heat 1.9 but found %+v", cell)
+		}
+	}
+
+	binned := game.HeatMap(nil, 10)
+	if len(binned.Cells) != 1 || binned.Cells[0].X != 100 || binned.Cells[0].Y != 100 ||
+		math.Abs(binned.Cells[0].Heat-7.6) > 1e-9 {
+		t.Errorf("Expected one bin at (100, 100) with heat 7.6 but found %v", binned.Cells)
+	}
+
+	outside := game.HeatMap(&Region{MinX: 0, MinY: 0, MaxX: 10, MaxY: 10}, 1)
+	if len(outside.Cells) != 0 {
+		t.Errorf("Expected no heat around the block but found %v", outside.Cells)
+	}
+
+	for i := 0; i < 100; i++ {
+		game.do(Operation{Type: OpEvolve})
+	}
+	if hot := game.HeatMap(nil, 1); len(hot.Cells) != 4 {
+		t.Errorf("Expected the blinker to stay hot but found %v", hot.Cells)
+	}
+}
+
+func TestBinStart(t *testing.T) {
+	testTable := []struct {
+		c, bin, start int64
+	}{
+		{c: 0, bin: 10, start: 0},
+		{c: 9, bin: 10, start: 0},
+		{c: -1, bin: 10, start: -10},
+		{c: -10, bin: 10, start: -10},
+		{c: math.MinInt64, bin: 3, start: math.MinInt64},
+		{c: math.MaxInt64, bin: 1, start: math.MaxInt64},
+	}
+	for _, testCase := range testTable {
+		if start := binStart(testCase.c, testCase.bin); start != testCase.start {
+			t.Errorf("Expected bin of %d to start at %d but found %d", testCase.c, testCase.start, start)
+		}
+	}
+}
+
+func TestHeatMapRequest(t *testing.T) {
+	testSrv := setUpServer([][2]int64{{0, 1}, {1, 1}, {2, 1}})
+	defer testSrv.Close()
+	resp, err := http.Post(buildUrl(testSrv.URL, "/generation/evolve/"), "", nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp.Body.Close()
+
+	testTable := []struct {
+		query  string
+		status int
+		cells  int
+	}{
+		{query: "", status: http.StatusOK, cells: 4},
+		{query: "?bin=2", status: http.StatusOK, cells: 3},
+		{query: "?min_x=0&min_y=0&max_x=1&max_y=1", status: http.StatusOK, cells: 2},
+		{query: "?min_x=0&min_y=0", status: http.StatusBadRequest},
+		{query: "?min_x=5&min_y=0&max_x=1&max_y=1", status: http.StatusBadRequest},
+		{query: "?bin=0", status: http.StatusBadRequest},
+		{query: "?bin=x", status: http.StatusBadRequest},
+	}
+	for _, testCase := range testTable {
+		resp, err := http.Get(buildUrl(testSrv.URL, "/heatmap/"+testCase.query))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		heatMap := HeatMap{}
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&heatMap); err != nil {
+				t.Fatalf("Error decoding json: %s", err)
+			}
+		}
+		resp.Body.Close()
+		if resp.StatusCode != testCase.status {
+			t.Errorf("Expected status %d for %s but found %d", testCase.status, testCase.query, resp.StatusCode)
+		} else if resp.StatusCode == http.StatusOK && len(heatMap.Cells) != testCase.cells {
+			t.Errorf("Expected %d cells for %s but found %v", testCase.cells, testCase.query, heatMap.Cells)
+		}
+	}
+}
+
+func TestEditAges(t *testing.T) {
+	block := [][2]int64{{0, 0}, {0, 1}, {1, 0}, {1, 1}}
+	far := [][2]int64{{100, 100}, {100, 101}, {101, 100}, {101, 101}}
+	game := NewGameOfLifeHandler(append(block, far...)).gameOfLife
+	for i := 0; i < 3; i++ {
+		game.do(Operation{Type: OpEvolve})
+	}
+	game.do(Operation{Type: OpRemoveCells, Cells: [][2]int64{{0, 0}}})
+	game.do(Operation{Type: OpAddCells, Cells: [][2]int64{{0, 1}, {5, 5}}})
+	game.do(Operation{Type: OpTransform, Transform: &Transform{
+		Region: &Region{MinX: 100, MinY: 100, MaxX: 101, MaxY: 101}, DX: 10, ClearSource: true}})
+
+	game.rwMutex.RLock()
+	defer game.rwMutex.RUnlock()
+	testTable := []struct {
+		x, y int64
+		age  int
+	}{
+		// the edits do not change the ages of the cells already alive
+		{x: 0, y: 1, age: 3},
+		{x: 1, y: 1, age: 3},
+		{x: 5, y: 5, age: 0},
+		{x: 0, y: 0, age: 0},
+		// the moved cells are new
+		{x: 110, y: 100, age: 0},
+		{x: 100, y: 100, age: 0},
+	}
+	for _, testCase := range testTable {
+		if age := game.age(testCase.x, testCase.y); age != testCase.age {
+			t.Errorf("Expected age %d for (%d, %d) but found %d", testCase.age, testCase.x, testCase.y, age)
+		}
+	}
+	for cell := range game.born {
+		if !game.isAlive(cell[0], cell[1]) {
+			t.Errorf("Expected the birth of the dead cell %v to be forgotten", cell)
+		}
+	}
+}
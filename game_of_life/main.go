@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Config - the settings of the game server.
+// Each one is read from a flag or from an environment variable
+type Config struct {
+	// the address to listen on - -addr or LIFE_ADDR
+	Addr string
+	// RLE or JSON file with the starting cells - -seed or LIFE_SEED
+	Seed string
+	// rule in the B/S notation - -rule or LIFE_RULE
+	Rule string
+	// the board is saved here on shutdown and read from here on start - -snapshot or LIFE_SNAPSHOT
+	Snapshot string
+	// every change is written to this operation log - -operations or LIFE_OPERATIONS
+	Operations string
+	// how long to wait for the running requests on shutdown - -shutdown-timeout or LIFE_SHUTDOWN_TIMEOUT
+	ShutdownTimeout time.Duration
+}
+
+// Reads the config from the command line arguments.
+// The environment variables give the defaults for the flags
+func loadConfig(args []string, getenv func(string) string) (Config, error) {
+	env := func(name string, fallback string) string {
+		if value := getenv(name); value != "" {
+			return value
+		}
+		return fallback
+	}
+	timeout, err := time.ParseDuration(env("LIFE_SHUTDOWN_TIMEOUT", "10s"))
+	if err != nil {
+		return Config{}, fmt.Errorf("LIFE_SHUTDOWN_TIMEOUT: %s", err)
+	}
+
+	config := Config{}
+	flags := flag.NewFlagSet("game_of_life", flag.ContinueOnError)
+	flags.StringVar(&config.Addr, "addr", env("LIFE_ADDR", ":8080"), "address to listen on")
+	flags.StringVar(&config.Seed, "seed", env("LIFE_SEED", ""), "RLE or JSON file with the starting cells")
+	flags.StringVar(&config.Rule, "rule", env("LIFE_RULE", ConwayRule.String()), "rule in the B/S notation")
+	flags.StringVar(&config.Snapshot, "snapshot", env("LIFE_SNAPSHOT", ""),
+		"file to save the board to on shutdown, it is used instead of the seed on start if it exists")
+	flags.StringVar(&config.Operations, "operations", env("LIFE_OPERATIONS", ""), "operation log file")
+	flags.DurationVar(&config.ShutdownTimeout, "shutdown-timeout", timeout,
+		"how long to wait for the running requests on shutdown")
+	err = flags.Parse(args)
+	return config, err
+}
+
+// Creates the handler described by the config
+func setUpHandler(config Config) (*GameOfLifeHandler, error) {
+	rule, err := ParseRule(config.Rule)
+	if err != nil {
+		return nil, err
+	}
+	options := []Option{WithRule(rule)}
+
+	var cells [][2]int64
+	snapshot, err := LoadSnapshot(config.Snapshot)
+	switch {
+	case config.Snapshot != "" && err == nil:
+		options = append(options, WithSnapshot(snapshot))
+	case config.Snapshot != "" && !os.IsNotExist(err):
+		return nil, err
+	case config.Seed != "":
+		if cells, err = LoadSeed(config.Seed); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Operations != "" {
+		operations, err := OpenOperationLog(config.Operations)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, WithOperationLog(operations))
+	}
+
+	return NewGameOfLifeHandler(cells, options...), nil
+}
+
+// Serves the game on listener until ctx is done.
+// Then the running requests are stopped, the board is saved
+// to the snapshot and the operation log is closed
+func serve(ctx context.Context, config Config, listener net.Listener) error {
+	handler, err := setUpHandler(config)
+	if err != nil {
+		return err
+	}
+	game := handler.gameOfLife
+
+	var ready int32 = 1
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		message(w, []byte("ok"), http.StatusOK)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&ready) == 0 {
+			message(w, []byte("shutting down"), http.StatusServiceUnavailable)
+			return
+		}
+		message(w, []byte("ok"), http.StatusOK)
+	})
+	mux.Handle("/", handler)
+
+	// cancelled on shutdown, so long evolutions stop between two generations
+	requests, stopRequests := context.WithCancel(context.Background())
+	defer stopRequests()
+	server := &http.Server{Handler: mux, BaseContext: func(net.Listener) context.Context {
+		return requests
+	}}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	select {
+	case err = <-served:
+	case <-ctx.Done():
+		atomic.StoreInt32(&ready, 0)
+		stopRequests()
+		shutdown, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		err = server.Shutdown(shutdown)
+		cancel()
+	}
+	if err == http.ErrServerClosed {
+		err = nil
+	}
+
+	if config.Snapshot != "" {
+		if saveErr := game.SaveSnapshot(config.Snapshot); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	if game.operations != nil {
+		if closeErr := game.operations.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func main() {
+	config, err := loadConfig(os.Args[1:], os.Getenv)
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", config.Addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("game of life listening on %s", listener.Addr())
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	if err := serve(ctx, config, listener); err != nil {
+		log.Fatal(err)
+	}
+	log.Print("game of life stopped")
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	env := map[string]string{
+		"LIFE_ADDR": ":9000",
+		"LIFE_RULE": "B36/S23",
+		"LIFE_SEED": "env.rle",
+	}
+	config, err := loadConfig([]string{"-seed", "flag.rle", "-snapshot", "board.json"},
+		func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := Config{Addr: ":9000", Seed: "flag.rle", Rule: "B36/S23",
+		Snapshot: "board.json", ShutdownTimeout: 10 * time.Second}
+	if config != expected {
+		t.Errorf("Expected %+v but found %+v", expected, config)
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	testTable := []struct {
+		notation, expected string
+	}{
+		{notation: "B3/S23", expected: "B3/S23"},
+		{notation: "b36/s23", expected: "B36/S23"},
+		{notation: "S23/B3", expected: "B3/S23"},
+		{notation: "23/3", expected: "B3/S23"},
+	}
+	for _, testCase := range testTable {
+		rule, err := ParseRule(testCase.notation)
+		if err != nil {
+			t.Errorf("Error parsing %s: %s", testCase.notation, err)
+		} else if rule.String() != testCase.expected {
+			t.Errorf("Expected %s for %s but found %s", testCase.expected, testCase.notation, rule)
+		}
+	}
+
+	for _, notation := range []string{"B3", "B9/S23", "B03/S23"} {
+		if _, err := ParseRule(notation); err == nil {
+			t.Errorf("Expected an error for %s", notation)
+		}
+	}
+}
+
+func TestHighLifeRule(t *testing.T) {
+	// a cell with 6 neighbours is born in HighLife, not in Conway's game
+	cells := [][2]int64{{0, 0}, {1, 0}, {2, 0}, {0, 2}, {1, 2}, {2, 2}}
+	highLife := NewGameOfLifeHandler(cells, WithRule(MustParseRule("B36/S23"))).gameOfLife
+	conway := NewGameOfLifeHandler(cells).gameOfLife
+	highLife.do(Operation{Type: OpEvolve})
+	conway.do(Operation{Type: OpEvolve})
+
+	if !highLife.isAlive(1, 1) {
+		t.Errorf("Expected (1, 1) to be born with B36/S23")
+	}
+	if conway.isAlive(1, 1) {
+		t.Errorf("Expected (1, 1) to stay dead with B3/S23")
+	}
+}
+
+func TestServeAndShutdown(t *testing.T) {
+	dir := t.TempDir()
+	seed := filepath.Join(dir, "seed.rle")
+	ioutil.WriteFile(seed, []byte("3o!"), 0644)
+	config := Config{Seed: seed, Rule: "B3/S23",
+		Snapshot:        filepath.Join(dir, "snapshot.json"),
+		Operations:      filepath.Join(dir, "operations.log"),
+		ShutdownTimeout: 5 * time.Second}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	url := "http://" + listener.Addr().String()
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, config, listener)
+	}()
+
+	for _, path := range []string{"/healthz", "/readyz", "/generation/"} {
+		resp, err := http.Get(url + path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200 for %s but found %d", path, resp.StatusCode)
+		}
+	}
+
+	// the blinker never dies, so this runs until the shutdown
+	evolved := make(chan EvolveResult, 1)
+	go func() {
+		resp, err := http.Post(url+"/generation/evolve-until/", "application/json",
+			bytes.NewBufferString(`{"empty": true}`))
+		result := EvolveResult{}
+		if err == nil {
+			json.NewDecoder(resp.Body).Decode(&result)
+			resp.Body.Close()
+		}
+		evolved <- result
+	}()
+	// the shutdown starts only after the evolution does
+	for generation, tries := 0, 0; generation == 0 && tries < 1000; tries++ {
+		resp, err := http.Get(url + "/generation/")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		current := Generation{}
+		json.NewDecoder(resp.Body).Decode(&current)
+		resp.Body.Close()
+		generation = current.Generation
+		time.Sleep(time.Millisecond)
+	}
+	// a connection dialed but never used would keep the server waiting
+	http.DefaultClient.CloseIdleConnections()
+	stop()
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("Expected a clean shutdown but found %s", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("The server did not stop")
+	}
+	if result := <-evolved; result.Reason != StoppedCancelled {
+		t.Errorf("Expected the evolution to be cancelled but found %+v", result)
+	}
+
+	snapshot, err := LoadSnapshot(config.Snapshot)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if snapshot.Generation == 0 || len(snapshot.Living) != 3 {
+		t.Errorf("Expected an evolved blinker in the snapshot but found %+v", snapshot)
+	}
+
+	replayed, err := Replay(config.Operations, -1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if replayed.generation != snapshot.Generation {
+		t.Errorf("Expected the operation log to reach generation %d but found %d",
+			snapshot.Generation, replayed.generation)
+	}
+
+	// the next start continues from the snapshot
+	handler, err := setUpHandler(config)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer handler.gameOfLife.operations.Close()
+	if handler.gameOfLife.generation != snapshot.Generation {
+		t.Errorf("Expected to start from generation %d but found %d",
+			snapshot.Generation, handler.gameOfLife.generation)
+	}
+}
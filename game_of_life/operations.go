@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Types of the operations changing the board
+const (
+	// the board is replaced with the given cells and generation,
+	// written when a handler starts using the log
+	OpSeed = "seed"
+	// the given cells become alive
+	OpAddCells = "add"
+	// the given cells die
+	OpRemoveCells = "remove"
+	// the next generation is calculated
+	OpEvolve = "evolve"
+	// the board is cleared
+	OpReset = "reset"
+	// the cells of a region are moved, rotated, mirrored or copied
+	OpTransform = "transform"
+)
+
+// Operation - a change of the board as written in the operation log
+type Operation struct {
+	// the position of the operation in the log, starting from 0
+	Index int       `json:"index"`
+	Time  time.Time `json:"time"`
+	Type  string    `json:"type"`
+	// who made the change - see clientName
+	Client string `json:"client,omitempty"`
+	// the remote address of the request
+	Address    string     `json:"address,omitempty"`
+	Cells      [][2]int64 `json:"cells,omitempty"`
+	Generation int        `json:"generation,omitempty"`
+	// the rule of the game, only in the seed
+	Rule      string     `json:"rule,omitempty"`
+	Transform *Transform `json:"transform,omitempty"`
+	// the session of the client making the edit
+	Session string `json:"session,omitempty"`
+	// the version of the board the edit was made for, it is refused on any other - see version
+	Version string `json:"version,omitempty"`
+	// the generation the edit was made for, it is refused on any other.
+	// Weaker than the version - the board also changes without evolving
+	Target *int `json:"target,omitempty"`
+}
+
+// The key of the remote address in the context of EvolveUntil
+type addressKey struct{}
+
+// Creates an operation made by the client of the request
+func requestOperation(r *http.Request, opType string, cells [][2]int64) Operation {
+	return Operation{Type: opType, Client: clientName(r), Address: r.RemoteAddr, Cells: cells}
+}
+
+// Writes the operation to the log and applies it to the board.
+// Nothing changes if the operation can not be written
+func (game *GameOfLife) do(op Operation) error {
+	game.pushMutex.Lock()
+	defer game.pushMutex.Unlock()
+	return game.perform(&op)
+}
+
+// Does the operation like do and sends it to the other sessions as an edit.
+// It is sent before the next operation starts, so the sessions get the edits in their order
+func (game *GameOfLife) doEdit(op Operation) error {
+	game.pushMutex.Lock()
+	defer game.pushMutex.Unlock()
+	if err := game.perform(&op); err != nil {
+		return err
+	}
+	// the address of a client is not shown to the others
+	op.Address = ""
+	game.broadcast(op.Session, EventEdit, op)
+	return nil
+}
+
+// Writes the operation to the log, where it gets its index, and applies it to the board
+// Has to be called while holding the pushMutex
+func (game *GameOfLife) perform(op *Operation) error {
+	// operations which can not be applied are not written
+	if err := game.check(*op); err != nil {
+		return err
+	}
+	if game.operations != nil {
+		if err := game.operations.append(op); err != nil {
+			return err
+		}
+	}
+	if err := game.apply(*op); err != nil {
+		return err
+	}
+	game.notify()
+	return nil
+}
+
+// Checks if the operation can be applied to the board
+// Has to be called while holding the pushMutex
+func (game *GameOfLife) check(op Operation) error {
+	if op.Version != "" || op.Target != nil {
+		game.rwMutex.RLock()
+		stale := op.Version != "" && op.Version != game.version() ||
+			op.Target != nil && *op.Target != game.generation
+		game.rwMutex.RUnlock()
+		if stale {
+			return ErrStaleEdit
+		}
+	}
+	if op.Type != OpTransform {
+		return nil
+	}
+	if op.Transform == nil {
+		return errors.New("transform operation without a transform")
+	}
+	game.rwMutex.RLock()
+	defer game.rwMutex.RUnlock()
+	_, _, err := game.transformed(*op.Transform)
+	return err
+}
+
+// Changes the board as the operation says
+// Has to be called while holding the pushMutex
+func (game *GameOfLife) apply(op Operation) error {
+	if op.Type == OpEvolve {
+		game.nextGeneration()
+		return nil
+	}
+
+	if op.Type == OpSeed && op.Rule != "" {
+		rule, err := ParseRule(op.Rule)
+		if err != nil {
+			return err
+		}
+		game.rule = &rule
+	}
+
+	cells := op.Cells
+	// the cells which may have changed, the ages and the owners of the others stay
+	changed := op.Cells
+	game.rwMutex.Lock()
+	switch op.Type {
+	case OpSeed:
+		game.board = make(map[int64]map[int64]bool)
+		game.generation = op.Generation
+		game.born, game.heat, game.owners = nil, nil, nil
+	case OpReset:
+		game.board = make(map[int64]map[int64]bool)
+		game.generation = 0
+		game.born, game.heat, game.owners = nil, nil, nil
+	case OpTransform:
+		moved, err := game.transform(*op.Transform)
+		if err != nil {
+			game.rwMutex.Unlock()
+			return err
+		}
+		changed = moved
+	case OpRemoveCells:
+		for _, cell := range op.Cells {
+			removeFromBoard(game.board, cell[0], cell[1])
+		}
+		cells = nil
+	}
+	for _, cell := range cells {
+		game.addCell(cell[0], cell[1])
+	}
+	if op.Type == OpAddCells {
+		game.own(op.Cells, op.Client)
+	}
+	game.trackEdit(changed)
+	game.forgetOwners(changed)
+	// the generation stays the same or goes back, so the board needs a new version
+	game.modification += 1
+	game.rwMutex.Unlock()
+	return nil
+}
+
+// OperationLog - an append only file with one JSON operation per line
+type OperationLog struct {
+	path  string
+	file  *os.File
+	mutex sync.Mutex
+	next  int
+	// the first failed write, after it nothing is written
+	err error
+}
+
+// Opens the operation log at path for appending, creating it if needed.
+// An operation cut in the middle of writing is removed from the end of the file
+func OpenOperationLog(path string) (*OperationLog, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	complete := bytes.LastIndexByte(data, '\n') + 1
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Truncate(int64(complete)); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(int64(complete), io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &OperationLog{path: path, file: file, next: bytes.Count(data[:complete], []byte{'\n'})}, nil
+}
+
+// Writes the operation at the end of the log, setting its index and time
+func (log *OperationLog) append(op *Operation) error {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	if log.err != nil {
+		return log.err
+	}
+
+	op.Index = log.next
+	op.Time = time.Now()
+	line, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	if _, err := log.file.Write(append(line, '\n')); err != nil {
+		log.err = fmt.Errorf("operation log is not writable: %s", err)
+		return log.err
+	}
+	log.next += 1
+	return nil
+}
+
+// Returns the path of the log file
+func (log *OperationLog) Path() string {
+	return log.path
+}
+
+// Flushes the log to the disk and closes it
+func (log *OperationLog) Close() error {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	if log.err == nil {
+		log.err = errors.New("operation log is closed")
+	}
+	if err := log.file.Sync(); err != nil {
+		log.file.Close()
+		return err
+	}
+	return log.file.Close()
+}
+
+// Writes every change of the board to the log.
+// The board is written first when the handler is created,
+// so the log can be replayed from its beginning
+func WithOperationLog(log *OperationLog) Option {
+	return func(handler *GameOfLifeHandler) {
+		handler.gameOfLife.operations = log
+	}
+}
+
+// Writes the current board and rule to the operation log
+func (game *GameOfLife) writeSeed() {
+	game.pushMutex.Lock()
+	defer game.pushMutex.Unlock()
+
+	game.rwMutex.RLock()
+	seed := Operation{Type: OpSeed, Client: "server", Cells: game.getLiving(),
+		Generation: game.generation, Rule: game.currentRule().String()}
+	game.rwMutex.RUnlock()
+	// a failure is remembered by the log and returned by the first change
+	game.operations.append(&seed)
+}
+
+// Rebuilds the board from the operation log at path applying
+// the operations up to and including index. A negative index applies all of them.
+// An operation cut in the middle of writing at the end of the log is ignored
+func Replay(path string, index int) (*GameOfLife, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	game := newGameOfLife()
+	game.pushMutex.Lock()
+	defer game.pushMutex.Unlock()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// the last line is complete only if it ends with a new line
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var op Operation
+		if err := json.Unmarshal(line, &op); err != nil {
+			return nil, fmt.Errorf("bad operation in %s: %s", path, err)
+		}
+		if index >= 0 && op.Index > index {
+			break
+		}
+		if err := game.apply(op); err != nil {
+			return nil, fmt.Errorf("bad operation %d in %s: %s", op.Index, path, err)
+		}
+	}
+	return game, nil
+}
+
+// Replays the whole operation log and compares the result with the board.
+// No changes are made while checking
+func (game *GameOfLife) VerifyOperations() (bool, error) {
+	if game.operations == nil {
+		return false, errors.New("there is no operation log")
+	}
+	game.pushMutex.Lock()
+	defer game.pushMutex.Unlock()
+
+	replayed, err := Replay(game.operations.Path(), -1)
+	if err != nil {
+		return false, err
+	}
+
+	game.rwMutex.RLock()
+	defer game.rwMutex.RUnlock()
+	return sameBoard(game, replayed), nil
+}
+
+// Checks if two games have the same generation and living cells
+func sameBoard(a *GameOfLife, b *GameOfLife) bool {
+	if a.generation != b.generation || a.population() != b.population() {
+		return false
+	}
+	for x, ym := range a.board {
+		for y, alive := range ym {
+			if alive && !b.isAlive(x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Type used for creating json for /operations/verify/ responses
+type Verification struct {
+	Matches bool `json:"matches"`
+}
+
+// Responsible to answer to /operations/replay/?index= requests
+// Answers with the generation and living cells after the operation with this index
+func (game *GameOfLife) replayOperations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if game.operations == nil {
+		http.Error(w, "There is no operation log", http.StatusNotFound)
+		return
+	}
+
+	index := -1
+	if indexStr := r.URL.Query().Get("index"); indexStr != "" {
+		var err error
+		if index, err = strconv.Atoi(indexStr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	replayed, err := Replay(game.operations.Path(), index)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	generation, _ := json.Marshal(Generation{Generation: replayed.generation, Living: replayed.getLiving()})
+	message(w, generation, http.StatusOK)
+}
+
+// Responsible to answer to /operations/verify/ requests
+func (game *GameOfLife) verifyOperations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if game.operations == nil {
+		http.Error(w, "There is no operation log", http.StatusNotFound)
+		return
+	}
+
+	matches, err := game.VerifyOperations()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	verification, _ := json.Marshal(Verification{Matches: matches})
+	message(w, verification, http.StatusOK)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOperationLogReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "operations.log")
+	log, err := OpenOperationLog(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer log.Close()
+
+	gofh := NewGameOfLifeHandler(blinker, WithOperationLog(log),
+		WithToken("secret", "teacher", ReadWrite))
+	testSrv := httptest.NewServer(gofh)
+	defer testSrv.Close()
+
+	postWithToken(t, buildUrl(testSrv.URL, "/cells/"), `[{"x": 10, "y": 10}]`)
+	postWithToken(t, buildUrl(testSrv.URL, "/generation/evolve/"), "")
+	postWithToken(t, buildUrl(testSrv.URL, "/generation/evolve/"), "")
+	postWithToken(t, buildUrl(testSrv.URL, "/reset/"), "")
+	postWithToken(t, buildUrl(testSrv.URL, "/cells/"), `[{"x": 1, "y": 2}, {"x": 3, "y": 4}]`)
+
+	matches, err := gofh.gameOfLife.VerifyOperations()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !matches {
+		t.Errorf("Expected the replayed board to match the live one")
+	}
+
+	testTable := []struct {
+		index      int
+		generation int
+		living     [][2]int64
+	}{
+		{index: 0, generation: 0, living: blinker},
+		{index: 1, generation: 0, living: [][2]int64{{0, 1}, {1, 1}, {2, 1}, {10, 10}}},
+		{index: 2, generation: 1, living: [][2]int64{{1, 0}, {1, 1}, {1, 2}}},
+		{index: 3, generation: 2, living: blinker},
+		{index: 4, generation: 0, living: [][2]int64{}},
+		{index: 5, generation: 0, living: [][2]int64{{1, 2}, {3, 4}}},
+	}
+	for _, testCase := range testTable {
+		replayed, err := Replay(path, testCase.index)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		expected := NewGameOfLifeHandler(testCase.living).gameOfLife
+		expected.generation = testCase.generation
+		if !sameBoard(replayed, expected) {
+			t.Errorf("Expected generation %d with %v at index %d but found generation %d with %v",
+				testCase.generation, testCase.living, testCase.index,
+				replayed.generation, replayed.getLiving())
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var op Operation
+	if err := json.Unmarshal([]byte(lines[1]), &op); err != nil {
+		t.Fatal(err.Error())
+	}
+	if op.Type != OpAddCells || op.Client != "teacher" || op.Address == "" || op.Time.IsZero() {
+		t.Errorf("Expected an add operation by teacher with address and time but found %+v", op)
+	}
+}
+
+func TestOperationLogTornWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "operations.log")
+	log, err := OpenOperationLog(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	game := NewGameOfLifeHandler(blinker, WithOperationLog(log)).gameOfLife
+	game.do(Operation{Type: OpEvolve})
+	log.Close()
+
+	// a crash in the middle of writing the next operation
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	file.Write([]byte(`{"index": 2, "type": "res`))
+	file.Close()
+
+	replayed, err := Replay(path, -1)
+	if err != nil {
+		t.Fatalf("Expected the cut operation to be ignored but found %s", err)
+	}
+	if replayed.generation != 1 {
+		t.Errorf("Expected generation 1 but found %d", replayed.generation)
+	}
+
+	log, err = OpenOperationLog(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer log.Close()
+	game = NewGameOfLifeHandler(nil, WithOperationLog(log)).gameOfLife
+	game.do(Operation{Type: OpAddCells, Cells: [][2]int64{{7, 7}}})
+
+	replayed, err = Replay(path, -1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !sameBoard(replayed, game) {
+		t.Errorf("Expected the log to continue after the cut operation but found %v", replayed.getLiving())
+	}
+	matches, _ := game.VerifyOperations()
+	if !matches {
+		t.Errorf("Expected the replayed board to match the live one")
+	}
+}
+
+func TestOperationsRequests(t *testing.T) {
+	testSrv := setUpServer(blinker)
+	defer testSrv.Close()
+
+	resp, err := http.Get(buildUrl(testSrv.URL, "/operations/verify/"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 without an operation log but found %d", resp.StatusCode)
+	}
+
+	log, err := OpenOperationLog(filepath.Join(t.TempDir(), "operations.log"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer log.Close()
+	logSrv := httptest.NewServer(NewGameOfLifeHandler(blinker, WithOperationLog(log)))
+	defer logSrv.Close()
+
+	resp, err = http.Post(buildUrl(logSrv.URL, "/generation/evolve/"), "text/plain", nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(buildUrl(logSrv.URL, "/operations/replay/?index=0"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+	respBytes, _ := ioutil.ReadAll(resp.Body)
+	generation := Generation{}
+	if err := json.Unmarshal(respBytes, &generation); err != nil {
+		t.Fatalf("Error decoding json: %s", err)
+	}
+	if generation.Generation != 0 || len(generation.Living) != 3 {
+		t.Errorf("Expected the seed at index 0 but found %s", string(respBytes))
+	}
+
+	resp, err = http.Get(buildUrl(logSrv.URL, "/operations/verify/"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+	respBytes, _ = ioutil.ReadAll(resp.Body)
+	if strings.TrimSpace(string(respBytes)) != `{"matches":true}` {
+		t.Errorf("Expected the log to match but found %s", string(respBytes))
+	}
+}
+
+// Posts to url with the token of the teacher and checks the request succeeded
+func postWithToken(t *testing.T, url string, body string) {
+	req, err := http.NewRequest("POST", url, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		t.Errorf("Expected %s to succeed but found status %d", url, resp.StatusCode)
+	}
+}
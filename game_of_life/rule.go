@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// Rule - how many living neighbours a cell needs to be born or to survive
+type Rule struct {
+	birth    [9]bool
+	survival [9]bool
+	// one more than the biggest count that matters, counting stops there
+	limit int
+}
+
+// The rule of Conway's game of life
+var ConwayRule = MustParseRule("B3/S23")
+
+// Parses a rule in the B/S notation like "B3/S23" or "B36/S23".
+// The S/B notation "23/3" is also accepted.
+// Rules giving birth with no neighbours are not supported on an infinite board
+func ParseRule(notation string) (Rule, error) {
+	rule := Rule{}
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(notation)), "/")
+	if len(parts) != 2 {
+		return rule, errors.New("rule must look like B3/S23: " + notation)
+	}
+
+	birth, survival := parts[0], parts[1]
+	if strings.HasPrefix(survival, "B") || strings.HasPrefix(birth, "S") {
+		birth, survival = survival, birth
+	} else if !strings.HasPrefix(birth, "B") {
+		// S/B notation without letters
+		birth, survival = survival, birth
+	}
+
+	if err := parseCounts(strings.TrimPrefix(birth, "B"), &rule.birth); err != nil {
+		return rule, err
+	}
+	if err := parseCounts(strings.TrimPrefix(survival, "S"), &rule.survival); err != nil {
+		return rule, err
+	}
+	if rule.birth[0] {
+		return rule, errors.New("rules with B0 are not supported: " + notation)
+	}
+
+	for count := 0; count <= 8; count++ {
+		if rule.birth[count] || rule.survival[count] {
+			rule.limit = count + 1
+		}
+	}
+	return rule, nil
+}
+
+// Same as ParseRule but panics on error
+func MustParseRule(notation string) Rule {
+	rule, err := ParseRule(notation)
+	if err != nil {
+		panic(err)
+	}
+	return rule
+}
+
+// Marks each digit of counts in the table
+func parseCounts(counts string, table *[9]bool) error {
+	for _, digit := range counts {
+		if digit < '0' || digit > '8' {
+			return errors.New("neighbour counts must be between 0 and 8: " + counts)
+		}
+		table[digit-'0'] = true
+	}
+	return nil
+}
+
+// Returns the rule in the B/S notation
+func (rule Rule) String() string {
+	notation := []byte("B")
+	for count, born := range rule.birth {
+		if born {
+			notation = append(notation, byte('0'+count))
+		}
+	}
+	notation = append(notation, "/S"...)
+	for count, survives := range rule.survival {
+		if survives {
+			notation = append(notation, byte('0'+count))
+		}
+	}
+	return string(notation)
+}
+
+// Evolves the board with the given rule instead of the standard one
+func WithRule(rule Rule) Option {
+	return func(handler *GameOfLifeHandler) {
+		handler.gameOfLife.rule = &rule
+	}
+}
+
+// Returns the rule of the game
+func (game *GameOfLife) currentRule() *Rule {
+	if game.rule == nil {
+		return &ConwayRule
+	}
+	return game.rule
+}
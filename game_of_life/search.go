@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// Match - a place where a pattern is found on the board
+type Match struct {
+	// the top left corner of the pattern on the board
+	X int64 `json:"x"`
+	Y int64 `json:"y"`
+	// how the pattern is turned - as in Transform, mirrored first and then rotated
+	Rotate int    `json:"rotate"`
+	Mirror string `json:"mirror,omitempty"`
+}
+
+// Type used to read the /search/ requests - either an RLE pattern or a list of points
+type SearchRequest struct {
+	RLE    string  `json:"rle,omitempty"`
+	Points []Point `json:"points,omitempty"`
+}
+
+// Type used for creating json for /search/ responses
+type SearchResult struct {
+	Matches []Match `json:"matches"`
+}
+
+// One way to turn a pattern, with its cells moved to start at (0, 0)
+type orientation struct {
+	rotate int
+	mirror string
+	// sorted by x and then by y, so the first one is the anchor
+	cells         [][2]int64
+	width, height int64
+}
+
+// Returns the distinct orientations of the pattern.
+// Symmetric patterns look the same in some of them, those are returned once
+func orientations(pattern [][2]int64) []orientation {
+	result := make([]orientation, 0, 8)
+	seen := make(map[string]bool)
+	region := boundingBox(pattern)
+	for _, mirror := range []string{"", MirrorHorizontal} {
+		for _, rotate := range []int{0, 90, 180, 270} {
+			o := orientation{rotate: rotate, mirror: mirror}
+			for _, cell := range pattern {
+				o.cells = append(o.cells, orient(cell, region, rotate, mirror))
+			}
+			sort.Slice(o.cells, func(i, j int) bool {
+				if o.cells[i][0] != o.cells[j][0] {
+					return o.cells[i][0] < o.cells[j][0]
+				}
+				return o.cells[i][1] < o.cells[j][1]
+			})
+			key, _ := json.Marshal(o.cells)
+			if seen[string(key)] {
+				continue
+			}
+			seen[string(key)] = true
+			o.width, o.height = region.MaxX-region.MinX, region.MaxY-region.MinY
+			if rotate == 90 || rotate == 270 {
+				o.width, o.height = o.height, o.width
+			}
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+// Returns where a cell of the region goes after mirroring and rotating the region,
+// relative to the top left corner of the result
+func orient(cell [2]int64, region Region, rotate int, mirror string) [2]int64 {
+	width, height := region.MaxX-region.MinX, region.MaxY-region.MinY
+	u, v := cell[0]-region.MinX, cell[1]-region.MinY
+	if mirror == MirrorHorizontal {
+		u = width - u
+	}
+	switch rotate {
+	case 90:
+		u, v = height-v, u
+	case 180:
+		u, v = width-u, height-v
+	case 270:
+		u, v = v, width-u
+	}
+	return [2]int64{u, v}
+}
+
+// The biggest pattern which can be searched for in each direction
+const maxPatternSize = 1 << 16
+
+// Returns every place where the pattern is on the board in any orientation.
+// A match is exact and isolated - the other cells in the bounding box of the
+// pattern and the cells right around it are dead.
+// Only the offsets putting a pattern cell on a living cell are checked and
+// only the living cells are counted in the box, so big sparse patterns are fast
+func (game *GameOfLife) Search(pattern [][2]int64) ([]Match, error) {
+	matches := make([]Match, 0)
+	pattern = uniqueCells(pattern)
+	if len(pattern) == 0 {
+		return matches, nil
+	}
+	region := boundingBox(pattern)
+	if uint64(region.MaxX-region.MinX) >= maxPatternSize || uint64(region.MaxY-region.MinY) >= maxPatternSize {
+		return nil, errors.New("the pattern is too big")
+	}
+
+	game.rwMutex.RLock()
+	defer game.rwMutex.RUnlock()
+
+	living := sortCells(game.getLiving())
+	for _, o := range orientations(pattern) {
+		anchor := o.cells[0]
+		for _, cell := range living {
+			// the pattern starts so that its anchor is on this cell
+			x, okX := addInt64(cell[0], -anchor[0])
+			y, okY := addInt64(cell[1], -anchor[1])
+			if okX && okY && game.matches(o, x, y, living) {
+				matches = append(matches, Match{X: x, Y: y, Rotate: o.rotate, Mirror: o.mirror})
+			}
+		}
+	}
+	return matches, nil
+}
+
+// Returns the cells without the repeated ones
+func uniqueCells(cells [][2]int64) [][2]int64 {
+	seen := make(map[[2]int64]bool)
+	unique := make([][2]int64, 0, len(cells))
+	for _, cell := range cells {
+		if !seen[cell] {
+			seen[cell] = true
+			unique = append(unique, cell)
+		}
+	}
+	return unique
+}
+
+// Checks if the oriented pattern is at (x, y) and nothing else is around it.
+// living are all the living cells, sorted
+// Has to be called while holding the rwMutex
+func (game *GameOfLife) matches(o orientation, x int64, y int64, living sortedCells) bool {
+	// the cells of the pattern are checked first, most places fail here
+	for _, cell := range o.cells {
+		cellX, okX := addInt64(x, cell[0])
+		cellY, okY := addInt64(y, cell[1])
+		if !okX || !okY || !game.isAlive(cellX, cellY) {
+			return false
+		}
+	}
+
+	// then the bounding box with a border of one cell must have no other living cells
+	box := Region{MinX: saturatedAdd(x, -1), MinY: saturatedAdd(y, -1),
+		MaxX: saturatedAdd(saturatedAdd(x, o.width), 1), MaxY: saturatedAdd(saturatedAdd(y, o.height), 1)}
+	return !living.moreThan(box, len(o.cells))
+}
+
+// Returns a + b, or the closest int64 if it does not fit
+func saturatedAdd(a int64, b int64) int64 {
+	if sum, ok := addInt64(a, b); ok {
+		return sum
+	}
+	if b < 0 {
+		return math.MinInt64
+	}
+	return math.MaxInt64
+}
+
+// Living cells ordered by x and then by y, so the cells in a region are found
+// without looking at each cell of the region
+type sortedCells [][2]int64
+
+// Sorts the cells in place
+func sortCells(cells [][2]int64) sortedCells {
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i][0] != cells[j][0] {
+			return cells[i][0] < cells[j][0]
+		}
+		return cells[i][1] < cells[j][1]
+	})
+	return cells
+}
+
+// Returns the index of the first cell at (x, y) or after it
+func (cells sortedCells) find(x int64, y int64) int {
+	return sort.Search(len(cells), func(i int) bool {
+		return cells[i][0] > x || cells[i][0] == x && cells[i][1] >= y
+	})
+}
+
+// Checks if there are more than limit cells in the region.
+// Only the cells in the region and the columns with living cells are visited
+func (cells sortedCells) moreThan(region Region, limit int) bool {
+	count := 0
+	i := cells.find(region.MinX, region.MinY)
+	for i < len(cells) && cells[i][0] <= region.MaxX {
+		x, y := cells[i][0], cells[i][1]
+		switch {
+		case y < region.MinY:
+			i = cells.find(x, region.MinY)
+		case y <= region.MaxY:
+			count += 1
+			if count > limit {
+				return true
+			}
+			i += 1
+		case x == math.MaxInt64:
+			return false
+		default:
+			// the rest of the column is below the region
+			i = cells.find(x+1, region.MinY)
+		}
+	}
+	return false
+}
+
+// Responsible to answer to /search/ requests
+// Finds the pattern in the body on the board
+func (game *GameOfLife) search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	defer r.Body.Close()
+	bytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	request := SearchRequest{}
+	if err := json.Unmarshal(bytes, &request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var cells [][2]int64
+	switch {
+	case request.RLE != "" && len(request.Points) > 0:
+		http.Error(w, "Either rle or points is expected, not both", http.StatusBadRequest)
+		return
+	case request.RLE != "":
+		if cells, err = ParseRLE([]byte(request.RLE)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		for _, p := range request.Points {
+			cells = append(cells, [2]int64{p.X, p.Y})
+		}
+	}
+	if len(cells) == 0 {
+		http.Error(w, "The pattern has no living cells", http.StatusBadRequest)
+		return
+	}
+
+	matches, err := game.Search(cells)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, _ := json.Marshal(SearchResult{Matches: matches})
+	message(w, result, http.StatusOK)
+}
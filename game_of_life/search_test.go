@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSearchGliders(t *testing.T) {
+	cells := [][2]int64{}
+	// a glider at (10, 20) as it is
+	for _, cell := range glider {
+		cells = append(cells, [2]int64{cell[0] + 10, cell[1] + 20})
+	}
+	// a glider at (-50, -50) mirrored left to right
+	for _, cell := range glider {
+		cells = append(cells, [2]int64{-50 + 2 - cell[0], -50 + cell[1]})
+	}
+	// a glider at (100, 100) with a living cell next to it is not isolated
+	for _, cell := range glider {
+		cells = append(cells, [2]int64{cell[0] + 100, cell[1] + 100})
+	}
+	cells = append(cells, [2]int64{99, 100})
+
+	game := NewGameOfLifeHandler(cells).gameOfLife
+	matches, err := game.Search(glider)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []Match{
+		{X: 10, Y: 20, Rotate: 0},
+		{X: -50, Y: -50, Rotate: 0, Mirror: MirrorHorizontal},
+	}
+	if len(matches) != len(expected) {
+		t.Fatalf("Expected %v but found %v", expected, matches)
+	}
+	for _, match := range expected {
+		if !containsMatch(matches, match.X, match.Y) {
+			t.Errorf("Expected a match at (%d, %d) but found %v", match.X, match.Y, matches)
+		}
+	}
+	for _, match := range matches {
+		if match.X == -50 && match.Mirror == "" && match.Rotate == 0 {
+			t.Errorf("Expected the glider at (-50, -50) to be turned but found %+v", match)
+		}
+	}
+}
+
+func TestSearchStillLife(t *testing.T) {
+	block := [][2]int64{{0, 0}, {0, 1}, {1, 0}, {1, 1}}
+	cells := [][2]int64{{5, 5}, {5, 6}, {6, 5}, {6, 6}, {0, 0}, {1, 0}, {2, 0}}
+	game := NewGameOfLifeHandler(cells).gameOfLife
+
+	matches, err := game.Search(block)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// the block looks the same in every orientation, so it is found once
+	if len(matches) != 1 || matches[0].X != 5 || matches[0].Y != 5 {
+		t.Errorf("Expected a single block at (5, 5) but found %v", matches)
+	}
+
+	// a part of the line is not an isolated match
+	matches, _ = game.Search([][2]int64{{0, 0}, {1, 0}})
+	if len(matches) != 0 {
+		t.Errorf("Expected no matches inside of a bigger pattern but found %v", matches)
+	}
+}
+
+func TestSearchRequest(t *testing.T) {
+	testSrv := setUpServer([][2]int64{{0, 1}, {1, 1}, {2, 1}})
+	defer testSrv.Close()
+
+	testTable := []struct {
+		body    string
+		status  int
+		matches int
+	}{
+		{body: `{"rle": "3o!"}`, status: http.StatusOK, matches: 1},
+		{body: `{"points": [{"x": 7, "y": 0}, {"x": 7, "y": 1}, {"x": 7, "y": 2}]}`, status: http.StatusOK, matches: 1},
+		{body: `{"rle": "2o!"}`, status: http.StatusOK, matches: 0},
+		{body: `{}`, status: http.StatusBadRequest},
+		{body: `{"rle": "o!", "points": [{"x": 0, "y": 0}]}`, status: http.StatusBadRequest},
+	}
+	for _, testCase := range testTable {
+		resp, err := http.Post(buildUrl(testSrv.URL, "/search/"), "application/json",
+			bytes.NewBufferString(testCase.body))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != testCase.status {
+			t.Errorf("Expected status %d for %s but found %d", testCase.status, testCase.body, resp.StatusCode)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			continue
+		}
+		result := SearchResult{}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Error decoding json: %s", err)
+		}
+		if len(result.Matches) != testCase.matches {
+			t.Errorf("Expected %d matches for %s but found %v", testCase.matches, testCase.body, result.Matches)
+		}
+	}
+}
+
+// Checks if there is a match at (x, y)
+func containsMatch(matches []Match, x int64, y int64) bool {
+	for _, match := range matches {
+		if match.X == x && match.Y == y {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSearchSparsePattern(t *testing.T) {
+	// the bounding box of the pattern has more than 4e9 cells
+	pattern := [][2]int64{{0, 0}, {maxPatternSize - 1, maxPatternSize - 1}}
+	cells := [][2]int64{{5, 5}, {5 + maxPatternSize - 1, 5 + maxPatternSize - 1},
+		{-200000, 0}, {-200000 + maxPatternSize - 1, maxPatternSize - 1}, {-199999, 1000}}
+	for i := int64(0); i < 1000; i++ {
+		cells = append(cells, [2]int64{i * 50, -1000 - i})
+	}
+	game := NewGameOfLifeHandler(cells).gameOfLife
+
+	start := time.Now()
+	matches, err := game.Search(pattern)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the search to be fast but it took %s", elapsed)
+	}
+	// the second pair has a living cell in its box
+	if len(matches) != 1 || !containsMatch(matches, 5, 5) {
+		t.Errorf("Expected a match at (5, 5) but found %v", matches)
+	}
+}
+
+func TestSearchAtTheEdge(t *testing.T) {
+	cells := [][2]int64{{math.MaxInt64, math.MaxInt64}, {math.MaxInt64 - 1, math.MaxInt64}}
+	game := NewGameOfLifeHandler(cells).gameOfLife
+	matches, err := game.Search([][2]int64{{0, 0}, {1, 0}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(matches) != 1 || !containsMatch(matches, math.MaxInt64-1, math.MaxInt64) {
+		t.Errorf("Expected a match at the edge of the board but found %v", matches)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Reads the living cells of a seed file.
+// Files ending in .rle are read as RLE, .json files as JSON.
+// Any other file is guessed by its first character
+func LoadSeed(path string) ([][2]int64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".rle":
+		return ParseRLE(data)
+	case ".json":
+		return ParseJSONSeed(data)
+	}
+	return ParseSeed(data)
+}
+
+// Parses a seed as JSON if it starts like JSON and as RLE otherwise
+func ParseSeed(data []byte) ([][2]int64, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return ParseJSONSeed(data)
+	}
+	return ParseRLE(data)
+}
+
+// Parses the JSON formats used by the handler:
+// a list of points as sent to /cells/, a list of pairs,
+// or a whole /generation/ response
+func ParseJSONSeed(data []byte) ([][2]int64, error) {
+	var pairs [][2]int64
+	if err := json.Unmarshal(data, &pairs); err == nil {
+		return pairs, nil
+	}
+
+	var points []Point
+	if err := json.Unmarshal(data, &points); err == nil {
+		cells := make([][2]int64, len(points))
+		for i, p := range points {
+			cells[i] = [2]int64{p.X, p.Y}
+		}
+		return cells, nil
+	}
+
+	var generation struct {
+		Living *[][2]int64 `json:"living"`
+	}
+	if err := json.Unmarshal(data, &generation); err != nil {
+		return nil, fmt.Errorf("unknown JSON seed format: %s", err)
+	}
+	if generation.Living == nil {
+		return nil, errors.New("unknown JSON seed format: no living cells")
+	}
+	return *generation.Living, nil
+}
+
+// Parses a pattern in the Run Length Encoded format.
+// The first row of the pattern is y = 0 and y grows downwards.
+// Any cell state other than 'b' and '.' is treated as alive
+func ParseRLE(data []byte) ([][2]int64, error) {
+	cells := make([][2]int64, 0)
+	var x, y int64
+	headerRead := false
+	// a run count may be split between two lines
+	count := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !headerRead && strings.HasPrefix(line, "x") {
+			// the header only describes the size and the rule
+			headerRead = true
+			continue
+		}
+		headerRead = true
+
+		for _, r := range line {
+			switch {
+			case r >= '0' && r <= '9':
+				count += string(r)
+				continue
+			case r == ' ' || r == '\t':
+				continue
+			case r == '!':
+				return cells, nil
+			}
+
+			run := int64(1)
+			if count != "" {
+				var err error
+				run, err = strconv.ParseInt(count, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				count = ""
+			}
+
+			switch r {
+			case 'b', '.':
+				x += run
+			case '$':
+				x = 0
+				y += run
+			default:
+				for i := int64(0); i < run; i++ {
+					cells = append(cells, [2]int64{x, y})
+					x++
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cells, nil
+}
+
+// Writes the generation and the living cells to path as a /generation/ response.
+// The file is replaced at once, so it is never left half written
+func (game *GameOfLife) SaveSnapshot(path string) error {
+	// no changes while saving
+	game.pushMutex.Lock()
+	game.rwMutex.RLock()
+	snapshot, err := json.Marshal(Generation{Generation: game.generation, Living: game.getLiving()})
+	game.rwMutex.RUnlock()
+	game.pushMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	temp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(snapshot); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Reads a snapshot written by SaveSnapshot
+func LoadSnapshot(path string) (Generation, error) {
+	snapshot := Generation{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return snapshot, err
+	}
+	err = json.Unmarshal(data, &snapshot)
+	return snapshot, err
+}
+
+// Starts the game from a snapshot instead of generation 0.
+// The snapshot cells are added to the start cells of the handler
+func WithSnapshot(snapshot Generation) Option {
+	return func(handler *GameOfLifeHandler) {
+		game := handler.gameOfLife
+		game.generation = snapshot.Generation
+		for _, cell := range snapshot.Living {
+			game.addCell(cell[0], cell[1])
+		}
+	}
+}
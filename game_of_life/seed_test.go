@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRLE(t *testing.T) {
+	rle := []byte(`#N Glider
+x = 3, y = 3, rule = B3/S23
+bob$2bo$
+3o!`)
+	cells, err := ParseRLE(rle)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := sortCells([][2]int64{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}})
+	if found := sortCells(cells); !reflect.DeepEqual(found, expected) {
+		t.Errorf("Expected %v but found %v", expected, found)
+	}
+}
+
+func TestParseJSONSeed(t *testing.T) {
+	expected := sortCells([][2]int64{{1, 2}, {-3, 4}})
+	seeds := []string{
+		`[[1, 2], [-3, 4]]`,
+		`[{"x": 1, "y": 2}, {"x": -3, "y": 4}]`,
+		`{"generation": 5, "living": [[1, 2], [-3, 4]]}`,
+	}
+	for _, seed := range seeds {
+		cells, err := ParseJSONSeed([]byte(seed))
+		if err != nil {
+			t.Errorf("Error parsing %s: %s", seed, err)
+			continue
+		}
+		if found := sortCells(cells); !reflect.DeepEqual(found, expected) {
+			t.Errorf("Expected %v for %s but found %v", expected, seed, found)
+		}
+	}
+
+	if _, err := ParseJSONSeed([]byte(`{"x": 1}`)); err == nil {
+		t.Errorf("Expected an error for an object without living cells")
+	}
+}
+
+func TestAddSeed(t *testing.T) {
+	gofh := NewGameOfLifeHandler(nil)
+	testSrv := httptest.NewServer(gofh)
+	defer testSrv.Close()
+
+	testTable := []struct {
+		seed   string
+		status int
+	}{
+		{seed: "x = 3, y = 1\n3o!", status: http.StatusCreated},
+		{seed: `[{"x": 10, "y": 10}]`, status: http.StatusCreated},
+		{seed: `{"x": 1}`, status: http.StatusBadRequest},
+	}
+	for _, testCase := range testTable {
+		resp, err := http.Post(buildUrl(testSrv.URL, "/cells/seed/"), "text/plain", strings.NewReader(testCase.seed))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != testCase.status {
+			t.Errorf("Expected status %d for %q but found %d", testCase.status, testCase.seed, resp.StatusCode)
+		}
+	}
+
+	game := gofh.gameOfLife
+	game.rwMutex.RLock()
+	defer game.rwMutex.RUnlock()
+	expected := sortCells([][2]int64{{0, 0}, {1, 0}, {2, 0}, {10, 10}})
+	if found := sortCells(game.getLiving()); !reflect.DeepEqual(found, expected) {
+		t.Errorf("Expected %v but found %v", expected, found)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returned when an edit is made for a version or a generation of the board which is not the current one
+var ErrStaleEdit = errors.New("the board has changed since the edit was made")
+
+// How long a session without an /events/ stream lasts after its last request
+const DefaultSessionTimeout = 10 * time.Minute
+
+// Session - a client editing the board together with the others.
+// A client can have many sessions, for example one in each browser tab
+type Session struct {
+	ID     string `json:"id"`
+	Client string `json:"client"`
+	// the cell the client points at, nil until it is set
+	Cursor *Point `json:"cursor,omitempty"`
+	// the time of the last request of the session
+	seen time.Time
+	// the session ends with its /events/ stream, it does not expire before that
+	streaming bool
+}
+
+// Sets how long a session without an /events/ stream lasts after its last request
+func WithSessionTimeout(timeout time.Duration) Option {
+	return func(handler *GameOfLifeHandler) {
+		handler.gameOfLife.sessionTimeout = timeout
+	}
+}
+
+// Type used for creating json for /sessions/ responses
+type Sessions struct {
+	Sessions []Session `json:"sessions"`
+}
+
+// Starts a new session of the client and tells the other sessions
+func (game *GameOfLife) join(client string) (Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Session{}, err
+	}
+	session := Session{ID: hex.EncodeToString(id), Client: client}
+
+	game.sessionsMutex.Lock()
+	expired := game.expireSessions()
+	if game.sessions == nil {
+		game.sessions = make(map[string]*Session)
+	}
+	session.seen = game.now()
+	game.sessions[session.ID] = &session
+	game.sessionsMutex.Unlock()
+
+	game.left(expired)
+	game.broadcast(session.ID, EventJoin, session)
+	return session, nil
+}
+
+// Ends the sessions which have not been used for longer than the session timeout
+// and returns them, so the others can be told with left
+// Has to be called while holding the sessionsMutex
+func (game *GameOfLife) expireSessions() []Session {
+	expired := make([]Session, 0)
+	now := game.now()
+	for id, session := range game.sessions {
+		if !session.streaming && now.Sub(session.seen) > game.sessionTimeout {
+			expired = append(expired, *session)
+			delete(game.sessions, id)
+		}
+	}
+	return expired
+}
+
+// Tells the other sessions that the sessions have ended
+func (game *GameOfLife) left(sessions []Session) {
+	for _, session := range sessions {
+		game.broadcast(session.ID, EventLeave, session)
+	}
+}
+
+// Keeps the session until its /events/ stream ends
+func (game *GameOfLife) startStream(id string) {
+	game.sessionsMutex.Lock()
+	defer game.sessionsMutex.Unlock()
+	if session, ok := game.sessions[id]; ok {
+		session.streaming = true
+	}
+}
+
+// Ends the session and tells the other sessions
+func (game *GameOfLife) leave(id string) {
+	game.sessionsMutex.Lock()
+	session, ok := game.sessions[id]
+	delete(game.sessions, id)
+	game.sessionsMutex.Unlock()
+
+	if ok {
+		game.broadcast(id, EventLeave, *session)
+	}
+}
+
+// Moves the cursor of the session and tells the other sessions
+func (game *GameOfLife) moveCursor(id string, cursor Point) {
+	game.sessionsMutex.Lock()
+	session, ok := game.sessions[id]
+	if ok {
+		session.Cursor = &cursor
+	}
+	game.sessionsMutex.Unlock()
+
+	if ok {
+		game.broadcast(id, EventCursor, Session{ID: id, Client: session.Client, Cursor: &cursor})
+	}
+}
+
+// Returns all the sessions ordered by their ids
+func (game *GameOfLife) Sessions() []Session {
+	game.sessionsMutex.Lock()
+	expired := game.expireSessions()
+	sessions := make([]Session, 0, len(game.sessions))
+	for _, session := range game.sessions {
+		sessions = append(sessions, *session)
+	}
+	game.sessionsMutex.Unlock()
+
+	game.left(expired)
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions
+}
+
+// Returns the session from the session query parameter, nil if there is none.
+// Only the client of the session can use it, each use keeps it from expiring.
+// Writes an error and returns false if it is not valid
+func (game *GameOfLife) requestSession(w http.ResponseWriter, r *http.Request) (*Session, bool) {
+	id := r.URL.Query().Get("session")
+	if id == "" {
+		return nil, true
+	}
+
+	game.sessionsMutex.Lock()
+	expired := game.expireSessions()
+	session, ok := game.sessions[id]
+	var found Session
+	if ok {
+		found = *session
+		if found.Client == clientName(r) {
+			session.seen = game.now()
+		}
+	}
+	game.sessionsMutex.Unlock()
+
+	game.left(expired)
+
+	if !ok {
+		http.Error(w, "Unknown session", http.StatusNotFound)
+		return nil, false
+	}
+	if found.Client != clientName(r) {
+		http.Error(w, "The session belongs to another client", http.StatusForbidden)
+		return nil, false
+	}
+	return &found, true
+}
+
+// Remembers the client as the creator of the cells
+// Has to be called while holding the rwMutex for writing
+func (game *GameOfLife) own(cells [][2]int64, client string) {
+	if client == "" {
+		return
+	}
+	if game.owners == nil {
+		game.owners = make(map[[2]int64]string)
+	}
+	for _, cell := range cells {
+		game.owners[cell] = client
+	}
+}
+
+// Forgets the creators of the cells which are not alive on the board
+// Has to be called while holding the rwMutex for writing
+func (game *GameOfLife) keepOwners(board map[int64]map[int64]bool) {
+	for cell := range game.owners {
+		if !board[cell[0]][cell[1]] {
+			delete(game.owners, cell)
+		}
+	}
+}
+
+// Forgets the creators of the edited cells which are not alive any more
+// Has to be called while holding the rwMutex for writing
+func (game *GameOfLife) forgetOwners(cells [][2]int64) {
+	for _, cell := range cells {
+		if !game.isAlive(cell[0], cell[1]) {
+			delete(game.owners, cell)
+		}
+	}
+}
+
+// Returns the client who created the living cell, "" if it was born or is dead
+// Has to be called while holding the rwMutex
+func (game *GameOfLife) owner(x int64, y int64) string {
+	return game.owners[[2]int64{x, y}]
+}
+
+// Makes an edit of the cells as the client of the request.
+// With the version query parameter or an If-Match header with the ETag of the board
+// the edit is refused with 409 if the board has changed since then,
+// so two edits made for the same board do not override each other.
+// With the generation query parameter it is refused if the board has evolved since then.
+// The other sessions get the edit as an event
+func (game *GameOfLife) edit(w http.ResponseWriter, r *http.Request, opType string, cells [][2]int64, status int) {
+	session, ok := game.requestSession(w, r)
+	if !ok {
+		return
+	}
+
+	op := requestOperation(r, opType, cells)
+	if session != nil {
+		op.Session = session.ID
+	}
+	if generationStr := r.URL.Query().Get("generation"); generationStr != "" {
+		target, err := strconv.Atoi(generationStr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		op.Target = &target
+	}
+	op.Version = r.URL.Query().Get("version")
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		op.Version = strings.Trim(strings.TrimPrefix(strings.TrimSpace(ifMatch), "W/"), "\"")
+	}
+
+	if err := game.doEdit(op); err == ErrStaleEdit {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	message(w, nil, status)
+}
+
+// Responsible to answer to /sessions/ requests
+// GET lists the sessions, POST starts a new one and DELETE ?session= ends one
+func (game *GameOfLife) sessionsRequest(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		sessions, _ := json.Marshal(Sessions{Sessions: game.Sessions()})
+		message(w, sessions, http.StatusOK)
+	case "POST":
+		session, err := game.join(clientName(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		created, _ := json.Marshal(session)
+		message(w, created, http.StatusCreated)
+	case "DELETE":
+		session, ok := game.requestSession(w, r)
+		if !ok {
+			return
+		}
+		if session == nil {
+			http.Error(w, "The session is required", http.StatusBadRequest)
+			return
+		}
+		game.leave(session.ID)
+		message(w, nil, http.StatusNoContent)
+	default:
+		http.Error(w, "Only GET, POST and DELETE methods are allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Responsible to answer to /sessions/cursor/?session= requests
+// Moves the cursor of the session to the point in the body
+func (game *GameOfLife) cursor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	session, ok := game.requestSession(w, r)
+	if !ok {
+		return
+	}
+	if session == nil {
+		http.Error(w, "The session is required", http.StatusBadRequest)
+		return
+	}
+
+	defer r.Body.Close()
+	bytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	point := Point{}
+	if err := json.Unmarshal(bytes, &point); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	game.moveCursor(session.ID, point)
+	message(w, nil, http.StatusNoContent)
+}
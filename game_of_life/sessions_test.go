@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStaleEdits(t *testing.T) {
+	testSrv := setUpServer([][2]int64{{0, 1}, {1, 1}, {2, 1}})
+	defer testSrv.Close()
+
+	version := func() string {
+		_, body := doWithToken(t, "GET", buildUrl(testSrv.URL, "/generation/"), "", "")
+		generation := Generation{}
+		json.Unmarshal(body, &generation)
+		return generation.Version
+	}
+	edit := func(path string, ifMatch string, status int) {
+		t.Helper()
+		req, _ := http.NewRequest("POST", buildUrl(testSrv.URL, path), bytes.NewBufferString(`[{"x": 50, "y": 50}]`))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("Expected status %d for %s %s but found %d", status, path, ifMatch, resp.StatusCode)
+		}
+	}
+
+	// two edits made for the same board in the same generation - the second one is refused
+	first := version()
+	edit("/cells/?version="+first, "", http.StatusCreated)
+	edit("/cells/?version="+first, "", http.StatusConflict)
+	edit("/cells/remove/?version="+first, "", http.StatusConflict)
+	edit("/cells/remove/?version="+version(), "", http.StatusNoContent)
+
+	doWithToken(t, "POST", buildUrl(testSrv.URL, "/generation/evolve/"), "", "")
+	edit("/cells/", `"`+first+`"`, http.StatusConflict)
+	edit("/cells/", `"`+version()+`"`, http.StatusCreated)
+	edit("/cells/?version=x", "", http.StatusConflict)
+	edit("/cells/", "", http.StatusCreated)
+
+	// the generation is a weaker check - only the evolution makes an edit stale
+	edit("/cells/?generation=1", "", http.StatusCreated)
+	edit("/cells/?generation=1", "", http.StatusCreated)
+	edit("/cells/remove/?generation=0", "", http.StatusConflict)
+	edit("/cells/?generation=1&version="+first, "", http.StatusConflict)
+	edit("/cells/?generation=one", "", http.StatusBadRequest)
+}
+
+func TestEditsAreBroadcastInOrder(t *testing.T) {
+	log, err := OpenOperationLog(filepath.Join(t.TempDir(), "operations.log"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer log.Close()
+	game := NewGameOfLifeHandler(nil, WithOperationLog(log)).gameOfLife
+	s, stop := game.subscribe("")
+	defer stop()
+
+	const clients, edits = 4, 15
+	var wait sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			for j := 0; j < edits; j++ {
+				game.doEdit(Operation{Type: OpAddCells, Cells: [][2]int64{{int64(i), int64(j)}}})
+			}
+		}(i)
+	}
+	wait.Wait()
+
+	// the events come in the order of the operation log, after the seed
+	for i := 1; i <= clients*edits; i++ {
+		m := <-s.messages
+		edit := Operation{}
+		json.Unmarshal(m.data, &edit)
+		if m.event != EventEdit || edit.Index != i {
+			t.Fatalf("Expected the edit %d but found %s %+v", i, m.event, edit)
+		}
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	gofh := NewGameOfLifeHandler(nil, WithSessionTimeout(time.Minute))
+	now := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	gofh.gameOfLife.now = func() time.Time { return now }
+	testSrv := httptest.NewServer(gofh)
+	defer testSrv.Close()
+
+	join := func() Session {
+		_, body := doWithToken(t, "POST", buildUrl(testSrv.URL, "/sessions/"), "", "")
+		session := Session{}
+		json.Unmarshal(body, &session)
+		return session
+	}
+	idle, used, streaming := join(), join(), join()
+	gofh.gameOfLife.startStream(streaming.ID)
+
+	now = now.Add(50 * time.Second)
+	resp, _ := doWithToken(t, "POST", buildUrl(testSrv.URL, "/sessions/cursor/?session="+used.ID), "", `{"x": 1, "y": 1}`)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status 204 but found %d", resp.StatusCode)
+	}
+
+	now = now.Add(20 * time.Second)
+	sessions := gofh.gameOfLife.Sessions()
+	found := map[string]bool{}
+	for _, session := range sessions {
+		found[session.ID] = true
+	}
+	if len(sessions) != 2 || found[idle.ID] || !found[used.ID] || !found[streaming.ID] {
+		t.Errorf("Expected only the idle session to expire but found %+v", sessions)
+	}
+	resp, _ = doWithToken(t, "POST", buildUrl(testSrv.URL, "/cells/?session="+idle.ID), "", `[]`)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an expired session but found %d", resp.StatusCode)
+	}
+}
+
+func TestCellOwners(t *testing.T) {
+	gofh := NewGameOfLifeHandler(nil,
+		WithToken("alice-token", "alice", ReadWrite), WithToken("bob-token", "bob", ReadWrite))
+	testSrv := httptest.NewServer(gofh)
+	defer testSrv.Close()
+
+	doWithToken(t, "POST", buildUrl(testSrv.URL, "/cells/"), "alice-token",
+		`[{"x": 0, "y": 1}, {"x": 1, "y": 1}, {"x": 2, "y": 1}]`)
+	doWithToken(t, "POST", buildUrl(testSrv.URL, "/cells/"), "bob-token", `[{"x": 2, "y": 1}, {"x": 9, "y": 9}]`)
+	doWithToken(t, "POST", buildUrl(testSrv.URL, "/cells/remove/"), "bob-token", `[{"x": 9, "y": 9}]`)
+
+	testTable := []struct {
+		x, y  int64
+		owner string
+	}{
+		{x: 0, y: 1, owner: "alice"},
+		// the last one to add a cell owns it
+		{x: 2, y: 1, owner: "bob"},
+		// removed cells have no owner
+		{x: 9, y: 9, owner: ""},
+	}
+	checkOwners := func() {
+		for _, testCase := range testTable {
+			_, body := doWithToken(t, "GET",
+				buildUrl(testSrv.URL, fmt.Sprintf("/cell/status/?x=%d&y=%d", testCase.x, testCase.y)), "bob-token", "")
+			status := Alive{}
+			if err := json.Unmarshal(body, &status); err != nil {
+				t.Fatalf("Error decoding json: %s", err)
+			}
+			if status.Owner != testCase.owner {
+				t.Errorf("Expected (%d, %d) to be owned by %q but found %q",
+					testCase.x, testCase.y, testCase.owner, status.Owner)
+			}
+		}
+	}
+	checkOwners()
+
+	doWithToken(t, "POST", buildUrl(testSrv.URL, "/generation/evolve/"), "bob-token", "")
+	testTable = []struct {
+		x, y  int64
+		owner string
+	}{
+		// the middle of the blinker survives
+		{x: 1, y: 1, owner: "alice"},
+		// born by evolving
+		{x: 1, y: 0, owner: ""},
+		// dead
+		{x: 0, y: 1, owner: ""},
+	}
+	checkOwners()
+
+	// the cells moved away from their owners are forgotten
+	gofh.gameOfLife.do(Operation{Type: OpTransform, Transform: &Transform{DX: 100, ClearSource: true}})
+	gofh.gameOfLife.rwMutex.RLock()
+	defer gofh.gameOfLife.rwMutex.RUnlock()
+	if len(gofh.gameOfLife.owners) != 0 {
+		t.Errorf("Expected no owners after moving the cells but found %v", gofh.gameOfLife.owners)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	gofh := NewGameOfLifeHandler(nil,
+		WithToken("alice-token", "alice", ReadWrite), WithToken("bob-token", "bob", ReadWrite))
+	testSrv := httptest.NewServer(gofh)
+	defer testSrv.Close()
+
+	alice := Session{}
+	resp, body := doWithToken(t, "POST", buildUrl(testSrv.URL, "/sessions/"), "alice-token", "")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201 but found %d", resp.StatusCode)
+	}
+	json.Unmarshal(body, &alice)
+	bob := Session{}
+	_, body = doWithToken(t, "POST", buildUrl(testSrv.URL, "/sessions/"), "bob-token", "")
+	json.Unmarshal(body, &bob)
+	if alice.Client != "alice" || bob.Client != "bob" || alice.ID == bob.ID {
+		t.Fatalf("Expected two different sessions but found %+v and %+v", alice, bob)
+	}
+
+	// bob watches the board
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", buildUrl(testSrv.URL, "/events/?session="+bob.ID), nil)
+	req.Header.Set("Authorization", "Bearer bob-token")
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer stream.Body.Close()
+	reader := bufio.NewReader(stream.Body)
+	waitForEvent(t, reader, EventGeneration)
+
+	// the sessions are used only by their clients
+	resp, _ = doWithToken(t, "POST", buildUrl(testSrv.URL, "/cells/?session="+alice.ID), "bob-token", `[]`)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403 but found %d", resp.StatusCode)
+	}
+	resp, _ = doWithToken(t, "POST", buildUrl(testSrv.URL, "/cells/?session=unknown"), "bob-token", `[]`)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 but found %d", resp.StatusCode)
+	}
+
+	doWithToken(t, "POST", buildUrl(testSrv.URL, "/cells/?session="+alice.ID), "alice-token",
+		`[{"x": 3, "y": 4}]`)
+	edit := Operation{}
+	json.Unmarshal([]byte(waitForEvent(t, reader, EventEdit)), &edit)
+	if edit.Client != "alice" || edit.Session != alice.ID || edit.Type != OpAddCells ||
+		len(edit.Cells) != 1 || edit.Cells[0] != [2]int64{3, 4} || edit.Address != "" {
+		t.Errorf("Expected the edit of alice but found %+v", edit)
+	}
+
+	doWithToken(t, "POST", buildUrl(testSrv.URL, "/sessions/cursor/?session="+alice.ID), "alice-token",
+		`{"x": 7, "y": -2}`)
+	cursor := Session{}
+	json.Unmarshal([]byte(waitForEvent(t, reader, EventCursor)), &cursor)
+	if cursor.ID != alice.ID || cursor.Cursor == nil || *cursor.Cursor != (Point{X: 7, Y: -2}) {
+		t.Errorf("Expected the cursor of alice but found %+v", cursor)
+	}
+
+	_, body = doWithToken(t, "GET", buildUrl(testSrv.URL, "/sessions/"), "alice-token", "")
+	sessions := Sessions{}
+	json.Unmarshal(body, &sessions)
+	if len(sessions.Sessions) != 2 {
+		t.Errorf("Expected 2 sessions but found %v", sessions.Sessions)
+	}
+
+	resp, _ = doWithToken(t, "DELETE", buildUrl(testSrv.URL, "/sessions/?session="+alice.ID), "alice-token", "")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status 204 but found %d", resp.StatusCode)
+	}
+	left := Session{}
+	json.Unmarshal([]byte(waitForEvent(t, reader, EventLeave)), &left)
+	if left.ID != alice.ID {
+		t.Errorf("Expected alice to leave but found %+v", left)
+	}
+
+	// the session of bob ends with his stream
+	cancel()
+	for i := 0; i < 100 && len(gofh.gameOfLife.Sessions()) != 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if sessions := gofh.gameOfLife.Sessions(); len(sessions) != 0 {
+		t.Errorf("Expected no sessions but found %v", sessions)
+	}
+}
+
+// Reads server-sent events until one of the given kind and returns its data
+func waitForEvent(t *testing.T, reader *bufio.Reader, event string) string {
+	current, data := "", ""
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Expected a %s event but found %s", event, err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			current = line[len("event: "):]
+		case strings.HasPrefix(line, "data: "):
+			data = line[len("data: "):]
+		case line == "":
+			if current == event {
+				return data
+			}
+			current, data = "", ""
+		}
+	}
+}
@@ -2,51 +2,109 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // GameOfLife - holds the state of the game
 type GameOfLife struct {
 	generation int
-	board      map[int64](map[int64]bool)
-	rwMutex    sync.RWMutex
-	pushMutex  sync.Mutex
+	// counts the changes of the board that do not change the generation
+	modification int
+	board        map[int64](map[int64]bool)
+	rwMutex      sync.RWMutex
+	pushMutex    sync.Mutex
+	// the standard B3/S23 rule is used if it is not set
+	rule *Rule
+	// every change of the board is written here if it is set
+	operations *OperationLog
+	// told about every change of the board - see subscribe
+	subscribers      map[*subscriber]bool
+	subscribersMutex sync.Mutex
+	// the clients editing the board together
+	sessions       map[string]*Session
+	sessionsMutex  sync.Mutex
+	sessionTimeout time.Duration
+	// the clock of the sessions - replaced in tests
+	now func() time.Time
+	// the client who created each living cell - see own
+	owners map[[2]int64]string
+	// the generation in which each living cell was born - see bornAt
+	born map[[2]int64]int
+	// how often each cell was born or died lately - see trackGeneration
+	heat map[[2]int64]float64
 }
 
 // GameOfLifeHandler - hold the game and multiplexer
 type GameOfLifeHandler struct {
 	mux        *http.ServeMux
-	gameOfLife GameOfLife
+	gameOfLife *GameOfLife
+	// access tokens, nobody needs a token if there are none
+	tokens  map[string]Token
+	limiter *rateLimiter
 }
 
+// Option - configures a GameOfLifeHandler when it is created
+type Option func(*GameOfLifeHandler)
+
 // Game of life implements Handler interface
 func (h *GameOfLifeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.mux.ServeHTTP(w, r)
 }
 
 // Creates new GameOfLifeHandler
-func NewGameOfLifeHandler(startCells [][2]int64) *GameOfLifeHandler {
-	gameOfLife := GameOfLife{generation: 0,
-		board: make(map[int64]map[int64]bool), rwMutex: sync.RWMutex{},
-		pushMutex: sync.Mutex{}}
+func NewGameOfLifeHandler(startCells [][2]int64, options ...Option) *GameOfLifeHandler {
+	gameOfLife := newGameOfLife()
 	for i := 0; i < len(startCells); i++ {
 		gameOfLife.addCell(startCells[i][0], startCells[i][1])
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/cell/status/", gameOfLife.getCellStatus)
-	mux.HandleFunc("/generation/", gameOfLife.getGeneration)
-	mux.HandleFunc("/cells/", gameOfLife.addCells)
-	mux.HandleFunc("/generation/evolve/", gameOfLife.evolve)
-	mux.HandleFunc("/reset/", gameOfLife.reset)
+	gameOfLifeHandler := &GameOfLifeHandler{gameOfLife: gameOfLife}
+	for _, option := range options {
+		option(gameOfLifeHandler)
+	}
+	if gameOfLife.operations != nil {
+		gameOfLife.writeSeed()
+	}
 
-	gameOfLifeHandler := GameOfLifeHandler{mux: mux, gameOfLife: gameOfLife}
+	guard := gameOfLifeHandler.guard
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cell/status/", guard(ReadOnly, false, gameOfLife.getCellStatus))
+	mux.HandleFunc("/generation/", guard(ReadOnly, false, gameOfLife.getGeneration))
+	mux.HandleFunc("/heatmap/", guard(ReadOnly, false, gameOfLife.getHeatMap))
+	mux.HandleFunc("/cells/", guard(ReadWrite, true, gameOfLife.addCells))
+	mux.HandleFunc("/cells/remove/", guard(ReadWrite, true, gameOfLife.removeCells))
+	mux.HandleFunc("/cells/seed/", guard(ReadWrite, true, gameOfLife.addSeed))
+	mux.HandleFunc("/generation/evolve/", guard(ReadWrite, true, gameOfLife.evolve))
+	mux.HandleFunc("/generation/evolve-until/", guard(ReadWrite, true, gameOfLife.evolveUntil))
+	mux.HandleFunc("/reset/", guard(ReadWrite, true, gameOfLife.reset))
+	mux.HandleFunc("/region/translate/", guard(ReadWrite, true, gameOfLife.translateRegion))
+	mux.HandleFunc("/region/rotate/", guard(ReadWrite, true, gameOfLife.rotateRegion))
+	mux.HandleFunc("/region/mirror/", guard(ReadWrite, true, gameOfLife.mirrorRegion))
+	mux.HandleFunc("/region/copy/", guard(ReadWrite, true, gameOfLife.copyRegion))
+	mux.HandleFunc("/search/", guard(ReadOnly, false, gameOfLife.search))
+	mux.HandleFunc("/operations/replay/", guard(ReadOnly, false, gameOfLife.replayOperations))
+	mux.HandleFunc("/operations/verify/", guard(ReadOnly, false, gameOfLife.verifyOperations))
+	mux.HandleFunc("/events/", guard(ReadOnly, false, gameOfLife.events))
+	mux.HandleFunc("/sessions/", guard(ReadOnly, true, gameOfLife.sessionsRequest))
+	mux.HandleFunc("/sessions/cursor/", guard(ReadOnly, true, gameOfLife.cursor))
+	mux.Handle("/ui/", uiHandler())
+	gameOfLifeHandler.mux = mux
+
+	return gameOfLifeHandler
+}
 
-	return &gameOfLifeHandler
+// Creates an empty game board
+func newGameOfLife() *GameOfLife {
+	return &GameOfLife{generation: 0,
+		board: make(map[int64]map[int64]bool), rwMutex: sync.RWMutex{},
+		pushMutex: sync.Mutex{}, sessionTimeout: DefaultSessionTimeout, now: time.Now}
 }
 
 // Add a living cell to the game board
@@ -64,6 +122,18 @@ func addToBoard(board map[int64]map[int64]bool, x int64, y int64) {
 	ym[y] = true
 }
 
+// Remove (x, y) from a board
+func removeFromBoard(board map[int64]map[int64]bool, x int64, y int64) {
+	ym, ok := board[x]
+	if !ok {
+		return
+	}
+	delete(ym, y)
+	if len(ym) == 0 {
+		delete(board, x)
+	}
+}
+
 // Check if cell (x, y) is alive
 func (game *GameOfLife) isAlive(x int64, y int64) bool {
 	ym, ok := game.board[x]
@@ -80,6 +150,10 @@ func (game *GameOfLife) isAlive(x int64, y int64) bool {
 // Type used for creating json for /cell/status/ requests
 type Alive struct {
 	Alive bool `json:"alive"`
+	// generations since the cell was born, 0 for dead cells
+	Age int `json:"age"`
+	// the client who added the cell, empty for the cells born by evolving
+	Owner string `json:"owner,omitempty"`
 }
 
 // Writes a response
@@ -96,6 +170,7 @@ func message(w http.ResponseWriter, message []byte, code int) {
 func (game *GameOfLife) getCellStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
 	}
 	xStr := r.URL.Query().Get("x")
 	yStr := r.URL.Query().Get("y")
@@ -108,7 +183,12 @@ func (game *GameOfLife) getCellStatus(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, errY.Error(), http.StatusBadRequest)
 	}
 	game.rwMutex.RLock()
-	alive, _ := json.Marshal(Alive{Alive: game.isAlive(x, y)})
+	etag := game.etag()
+	if notModified(w, r, etag) {
+		game.rwMutex.RUnlock()
+		return
+	}
+	alive, _ := json.Marshal(Alive{Alive: game.isAlive(x, y), Age: game.age(x, y), Owner: game.owner(x, y)})
 	game.rwMutex.RUnlock()
 
 	message(w, alive, http.StatusOK)
@@ -118,20 +198,60 @@ func (game *GameOfLife) getCellStatus(w http.ResponseWriter, r *http.Request) {
 type Generation struct {
 	Generation int        `json:"generation"`
 	Living     [][2]int64 `json:"living"`
+	// the version of the board the edits can target - see version
+	Version string `json:"version,omitempty"`
 }
 
 // Responsible to answer to /generation/ requests
 func (game *GameOfLife) getGeneration(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
 	}
 	game.rwMutex.RLock()
-	generation, _ := json.Marshal(Generation{Generation: game.generation, Living: game.getLiving()})
+	etag := game.etag()
+	if notModified(w, r, etag) {
+		game.rwMutex.RUnlock()
+		return
+	}
+	generation, _ := json.Marshal(Generation{Generation: game.generation, Living: game.getLiving(),
+		Version: game.version()})
 	game.rwMutex.RUnlock()
 	message(w, generation, http.StatusOK)
 
 }
 
+// Returns the version of the current board, it is different after each change.
+// The board changes either by evolving (generation) or by adding cells (modification)
+// Has to be called while holding the rwMutex
+func (game *GameOfLife) version() string {
+	return fmt.Sprintf("%d-%d", game.generation, game.modification)
+}
+
+// Returns the entity tag of the current board - its quoted version
+// Has to be called while holding the rwMutex
+func (game *GameOfLife) etag() string {
+	return "\"" + game.version() + "\""
+}
+
+// Sets the ETag header and checks it against If-None-Match
+// Writes 304 and returns true if the client already has the current board
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
 // Returns all the living cells on the game board
 func (game *GameOfLife) getLiving() [][2]int64 {
 	living := make([][2]int64, 0)
@@ -152,17 +272,80 @@ type Point struct {
 	Y int64 `json:"y"`
 }
 
-// Responsible to answer to /cells/ requests
+// A rectangle on the board, the borders are included
+type Region struct {
+	MinX int64 `json:"min_x"`
+	MinY int64 `json:"min_y"`
+	MaxX int64 `json:"max_x"`
+	MaxY int64 `json:"max_y"`
+}
+
+// Checks if (x, y) is in the region
+func (region Region) contains(x int64, y int64) bool {
+	return x >= region.MinX && x <= region.MaxX && y >= region.MinY && y <= region.MaxY
+}
+
+// Responsible to answer to /cells/?session=&version= requests
 func (game *GameOfLife) addCells(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cells, ok := readPoints(w, r)
+	if !ok {
+		return
 	}
+	game.edit(w, r, OpAddCells, cells, http.StatusCreated)
+}
 
+// Responsible to answer to /cells/seed/?session=&version= requests
+// The cells of the RLE or JSON seed file in the body become alive, as with /cells/.
+// This way the clients do not need to read the seed files themselves
+func (game *GameOfLife) addSeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	defer r.Body.Close()
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cells, err := ParseSeed(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	game.edit(w, r, OpAddCells, cells, http.StatusCreated)
+}
+
+// Responsible to answer to /cells/remove/?session=&version= requests
+// The given cells die, the dead ones stay dead
+func (game *GameOfLife) removeCells(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cells, ok := readPoints(w, r)
+	if !ok {
+		return
+	}
+	game.edit(w, r, OpRemoveCells, cells, http.StatusNoContent)
+}
+
+// Reads a json list of points from the body of the request.
+// Writes an error and returns false if it is not valid
+func readPoints(w http.ResponseWriter, r *http.Request) ([][2]int64, bool) {
 	defer r.Body.Close()
 	bytes, err := ioutil.ReadAll(r.Body)
 
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
 	}
 
 	s := make([]Point, 0)
@@ -170,36 +353,44 @@ func (game *GameOfLife) addCells(w http.ResponseWriter, r *http.Request) {
 
 	if err1 != nil {
 		http.Error(w, err1.Error(), http.StatusBadRequest)
+		return nil, false
 	}
 
-	game.pushMutex.Lock()
-	game.rwMutex.Lock()
-	for _, p := range s {
-		game.addCell(p.X, p.Y)
+	cells := make([][2]int64, len(s))
+	for i, p := range s {
+		cells[i] = [2]int64{p.X, p.Y}
 	}
-	game.rwMutex.Unlock()
-	game.pushMutex.Unlock()
-
-	message(w, nil, http.StatusCreated)
+	return cells, true
 }
 
 // Responsible to answer to /generation/evolve/ requests
 func (game *GameOfLife) evolve(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := game.do(requestOperation(r, OpEvolve, nil)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	// has to lock here for a little while
-	game.pushMutex.Lock()
+	message(w, nil, http.StatusNoContent)
+}
+
+// Calculates the next generation and replaces the board with it
+// Has to be called while holding the pushMutex
+func (game *GameOfLife) nextGeneration() {
 	game.rwMutex.RLock()
 
 	newBoard := make(map[int64]map[int64]bool)
+	rule := game.currentRule()
 
 	for x, ym := range game.board {
 		for y, alive := range ym {
 			if alive {
 				count := game.getLivingNeighbours(x, y)
-				if count == 2 || count == 3 {
+				if rule.survival[count] {
 					addToBoard(newBoard, x, y)
 				}
 
@@ -213,15 +404,15 @@ func (game *GameOfLife) evolve(w http.ResponseWriter, r *http.Request) {
 
 	// it is unwise to allow reading at this point, so lock again
 	game.rwMutex.Lock()
+	game.trackGeneration(newBoard)
+	game.keepOwners(newBoard)
 	game.generation += 1
 	game.board = newBoard
 	game.rwMutex.Unlock()
-	game.pushMutex.Unlock()
-
-	message(w, nil, http.StatusNoContent)
 }
 
-// Returns the number of living neighbours around a cell. Counts only to 4 to be more efficient
+// Returns the number of living neighbours around a cell.
+// Counts only to one more than the rule cares about to be more efficient - 4 for the standard rule
 func (game *GameOfLife) getLivingNeighbours(x int64, y int64) (count int) {
 	var min int64 = math.MinInt64
 	var max int64 = math.MaxInt64
@@ -243,6 +434,7 @@ func (game *GameOfLife) getLivingNeighbours(x int64, y int64) (count int) {
 	}
 
 	count = 0
+	limit := game.currentRule().limit
 
 	for i := minX; i <= maxX; i++ {
 		for j := minY; j <= maxY; j++ {
@@ -251,8 +443,8 @@ func (game *GameOfLife) getLivingNeighbours(x int64, y int64) (count int) {
 			}
 			if game.isAlive(i, j) {
 				count += 1
-				// no reason to check for more than 4 alive neighbours since the cell is overcrowded
-				if count == 4 {
+				// no reason to check for more alive neighbours since the cell is overcrowded
+				if count == limit {
 					return count
 				}
 			}
@@ -291,7 +483,7 @@ func (game *GameOfLife) addBornCellsAround(newBoard map[int64]map[int64]bool, x
 			if !game.isAlive(i, j) {
 				// dead cell found - count its neighbours
 				count := game.getLivingNeighbours(i, j)
-				if count == 3 {
+				if game.currentRule().birth[count] {
 					addToBoard(newBoard, i, j)
 				}
 			}
@@ -303,13 +495,13 @@ func (game *GameOfLife) addBornCellsAround(newBoard map[int64]map[int64]bool, x
 func (game *GameOfLife) reset(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := game.do(requestOperation(r, OpReset, nil)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-	game.pushMutex.Lock()
-	game.rwMutex.Lock()
-	game.generation = 0
-	game.board = make(map[int64]map[int64]bool)
-	game.rwMutex.Unlock()
-	game.pushMutex.Unlock()
 
 	message(w, nil, http.StatusNoContent)
 }
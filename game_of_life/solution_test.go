@@ -356,6 +356,69 @@ func TestBadQuery(t *testing.T) {
 	}
 }
 
+func TestGenerationETag(t *testing.T) {
+	testSrv := setUpServer([][2]int64{
+		{0, 0},
+		{1, 1},
+	})
+	defer testSrv.Close()
+
+	url := buildUrl(testSrv.URL, "/generation/")
+	etag := getETag(t, url, "", http.StatusOK)
+	if etag == "" {
+		t.Fatalf("Expected an ETag header but there is none")
+	}
+
+	getETag(t, url, etag, http.StatusNotModified)
+
+	var data = []byte(`[{"x": 42, "y": 43}]`)
+	resp, err := http.Post(buildUrl(testSrv.URL, "/cells/"), "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp.Body.Close()
+
+	added := getETag(t, url, etag, http.StatusOK)
+	if added == etag {
+		t.Errorf("Expected the ETag to change after adding cells but it is still %s", etag)
+	}
+
+	resp, err = http.Post(buildUrl(testSrv.URL, "/generation/evolve/"), "text/plain", nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp.Body.Close()
+
+	evolved := getETag(t, url, added, http.StatusOK)
+	if evolved == added {
+		t.Errorf("Expected the ETag to change after evolving but it is still %s", added)
+	}
+
+	resp, err = http.Post(buildUrl(testSrv.URL, "/reset/"), "text/plain", nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp.Body.Close()
+
+	reset := getETag(t, url, etag, http.StatusOK)
+	if reset == etag || reset == added || reset == evolved {
+		t.Errorf("Expected a new ETag after reset but found %s", reset)
+	}
+}
+
+func TestCellStatusETag(t *testing.T) {
+	testSrv := setUpServer([][2]int64{
+		{0, 0},
+	})
+	defer testSrv.Close()
+
+	url := buildUrl(testSrv.URL, "/cell/status/?x=0&y=0")
+	etag := getETag(t, url, "", http.StatusOK)
+	getETag(t, url, "\"nope\", "+etag, http.StatusNotModified)
+	getETag(t, url, "*", http.StatusNotModified)
+	getETag(t, url, "\"nope\"", http.StatusOK)
+}
+
 /* Utility functions */
 
 func buildUrl(baseUrl, path string) string {
@@ -368,3 +431,25 @@ func setUpServer(cells [][2]int64) *httptest.Server {
 	gofh := NewGameOfLifeHandler(cells)
 	return httptest.NewServer(gofh)
 }
+
+// Makes a GET request with the given If-None-Match header and checks the status.
+// Returns the ETag of the response.
+func getETag(t *testing.T, url, ifNoneMatch string, expectedStatus int) string {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		t.Errorf("Expected status %d for %s but found %d", expectedStatus, url, resp.StatusCode)
+	}
+	return resp.Header.Get("ETag")
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http"
+)
+
+// Ways to mirror a region
+const (
+	// left becomes right
+	MirrorHorizontal = "horizontal"
+	// top becomes bottom
+	MirrorVertical = "vertical"
+)
+
+// Returned when a transformed cell would not fit in int64
+var ErrOutOfBoard = errors.New("the transformed cells do not fit on the board")
+
+// Transform - moves, rotates, mirrors or copies the cells of a region.
+// The region is mirrored first, then rotated and then moved by DX, DY.
+// The result keeps the top left corner of the region, so a region
+// rotated in place without a move starts where it used to.
+type Transform struct {
+	// the cells to transform, the whole board if not set
+	Region *Region `json:"region,omitempty"`
+	// clockwise rotation in degrees - 0, 90, 180 or 270
+	Rotate int `json:"rotate,omitempty"`
+	// MirrorHorizontal, MirrorVertical or "" for none
+	Mirror string `json:"mirror,omitempty"`
+	DX     int64  `json:"dx,omitempty"`
+	DY     int64  `json:"dy,omitempty"`
+	// the cells of the region are removed before the result is added,
+	// otherwise the result is a copy
+	ClearSource bool `json:"clear_source,omitempty"`
+}
+
+// Checks the fields which do not depend on the board
+func (t Transform) validate() error {
+	if t.Rotate != 0 && t.Rotate != 90 && t.Rotate != 180 && t.Rotate != 270 {
+		return errors.New("rotate must be 0, 90, 180 or 270")
+	}
+	if t.Mirror != "" && t.Mirror != MirrorHorizontal && t.Mirror != MirrorVertical {
+		return errors.New("mirror must be horizontal or vertical")
+	}
+	if t.Region != nil && (t.Region.MinX > t.Region.MaxX || t.Region.MinY > t.Region.MaxY) {
+		return errors.New("region min must not be bigger than max")
+	}
+	return nil
+}
+
+// Applies the transform to the board.
+// Returns the cells which may have changed - the region and where it goes.
+// Nothing changes if any of the cells does not fit in int64
+// Has to be called while holding the rwMutex for writing
+func (game *GameOfLife) transform(t Transform) ([][2]int64, error) {
+	source, result, err := game.transformed(t)
+	if err != nil {
+		return nil, err
+	}
+	if t.ClearSource {
+		for _, cell := range source {
+			removeFromBoard(game.board, cell[0], cell[1])
+		}
+	}
+	for _, cell := range result {
+		game.addCell(cell[0], cell[1])
+	}
+	return append(source, result...), nil
+}
+
+// Returns the living cells of the region and where they go after the transform
+// Has to be called while holding the rwMutex
+func (game *GameOfLife) transformed(t Transform) (source [][2]int64, result [][2]int64, err error) {
+	if err := t.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var region Region
+	if t.Region != nil {
+		region = *t.Region
+		for _, cell := range game.getLiving() {
+			if region.contains(cell[0], cell[1]) {
+				source = append(source, cell)
+			}
+		}
+	} else {
+		source = game.getLiving()
+		if len(source) == 0 {
+			return nil, nil, nil
+		}
+		region = boundingBox(source)
+	}
+
+	// the corner of the result
+	baseX, okX := addInt64(region.MinX, t.DX)
+	baseY, okY := addInt64(region.MinY, t.DY)
+	if !okX || !okY {
+		return nil, nil, ErrOutOfBoard
+	}
+
+	// the sizes and the positions in the region are unsigned,
+	// so they do not overflow even for a region as big as the board
+	width := uint64(region.MaxX - region.MinX)
+	height := uint64(region.MaxY - region.MinY)
+	result = make([][2]int64, 0, len(source))
+	for _, cell := range source {
+		u := uint64(cell[0] - region.MinX)
+		v := uint64(cell[1] - region.MinY)
+
+		switch t.Mirror {
+		case MirrorHorizontal:
+			u = width - u
+		case MirrorVertical:
+			v = height - v
+		}
+		switch t.Rotate {
+		case 90:
+			u, v = height-v, u
+		case 180:
+			u, v = width-u, height-v
+		case 270:
+			u, v = v, width-u
+		}
+
+		x, okX := offset(baseX, u)
+		y, okY := offset(baseY, v)
+		if !okX || !okY {
+			return nil, nil, ErrOutOfBoard
+		}
+		result = append(result, [2]int64{x, y})
+	}
+	return source, result, nil
+}
+
+// Returns the smallest region containing all the cells
+func boundingBox(cells [][2]int64) Region {
+	region := Region{MinX: math.MaxInt64, MinY: math.MaxInt64, MaxX: math.MinInt64, MaxY: math.MinInt64}
+	for _, cell := range cells {
+		if cell[0] < region.MinX {
+			region.MinX = cell[0]
+		}
+		if cell[0] > region.MaxX {
+			region.MaxX = cell[0]
+		}
+		if cell[1] < region.MinY {
+			region.MinY = cell[1]
+		}
+		if cell[1] > region.MaxY {
+			region.MaxY = cell[1]
+		}
+	}
+	return region
+}
+
+// Adds two int64 numbers. The result is not ok if it overflows
+func addInt64(a int64, b int64) (int64, bool) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, false
+	}
+	return sum, true
+}
+
+// Adds an unsigned offset to base. The result is not ok if it is bigger than MaxInt64
+func offset(base int64, u uint64) (int64, bool) {
+	// the distance to MaxInt64 always fits in uint64
+	if u > uint64(math.MaxInt64)-uint64(base) {
+		return 0, false
+	}
+	return int64(uint64(base) + u), true
+}
+
+// Applies the transform to the board at once.
+// Returns ErrOutOfBoard and changes nothing if a cell would not fit in int64
+func (game *GameOfLife) ApplyTransform(t Transform) error {
+	return game.do(Operation{Type: OpTransform, Transform: &t})
+}
+
+// Applies the transform as one operation of the client
+func (game *GameOfLife) transformRequest(w http.ResponseWriter, r *http.Request, t Transform) {
+	if err := t.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	op := requestOperation(r, OpTransform, nil)
+	op.Transform = &t
+	if err := game.do(op); err == ErrOutOfBoard {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	message(w, nil, http.StatusNoContent)
+}
+
+// Reads the transform from the body of a POST request.
+// Writes an error and returns false if it is not valid
+func readTransform(w http.ResponseWriter, r *http.Request) (Transform, bool) {
+	t := Transform{}
+	if r.Method != "POST" {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return t, false
+	}
+
+	defer r.Body.Close()
+	bytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return t, false
+	}
+	if err := json.Unmarshal(bytes, &t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return t, false
+	}
+	return t, true
+}
+
+// Responsible to answer to /region/translate/ requests
+// Moves the cells of the region by dx, dy
+func (game *GameOfLife) translateRegion(w http.ResponseWriter, r *http.Request) {
+	t, ok := readTransform(w, r)
+	if !ok {
+		return
+	}
+	game.transformRequest(w, r, Transform{Region: t.Region, DX: t.DX, DY: t.DY, ClearSource: true})
+}
+
+// Responsible to answer to /region/rotate/ requests
+// Rotates the cells of the region clockwise in place
+func (game *GameOfLife) rotateRegion(w http.ResponseWriter, r *http.Request) {
+	t, ok := readTransform(w, r)
+	if !ok {
+		return
+	}
+	game.transformRequest(w, r, Transform{Region: t.Region, Rotate: t.Rotate, ClearSource: true})
+}
+
+// Responsible to answer to /region/mirror/ requests
+// Mirrors the cells of the region in place
+func (game *GameOfLife) mirrorRegion(w http.ResponseWriter, r *http.Request) {
+	t, ok := readTransform(w, r)
+	if !ok {
+		return
+	}
+	if t.Mirror == "" {
+		http.Error(w, "mirror must be horizontal or vertical", http.StatusBadRequest)
+		return
+	}
+	game.transformRequest(w, r, Transform{Region: t.Region, Mirror: t.Mirror, ClearSource: true})
+}
+
+// Responsible to answer to /region/copy/ requests
+// Copies the cells of the region to dx, dy away - rotated and mirrored if asked.
+// With clear_source the region is moved instead
+func (game *GameOfLife) copyRegion(w http.ResponseWriter, r *http.Request) {
+	t, ok := readTransform(w, r)
+	if !ok {
+		return
+	}
+	game.transformRequest(w, r, t)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+)
+
+func TestApplyTransform(t *testing.T) {
+	// an L inside the region (0, 0) - (2, 1) and a cell outside of it
+	cells := [][2]int64{{0, 0}, {0, 1}, {1, 1}, {2, 1}, {10, 10}}
+	region := &Region{MinX: 0, MinY: 0, MaxX: 2, MaxY: 1}
+
+	testTable := []struct {
+		name      string
+		transform Transform
+		expected  [][2]int64
+	}{
+		{name: "translate",
+			transform: Transform{Region: region, DX: 5, DY: -1, ClearSource: true},
+			expected:  [][2]int64{{5, -1}, {5, 0}, {6, 0}, {7, 0}, {10, 10}}},
+		{name: "copy",
+			transform: Transform{Region: region, DX: 0, DY: 3},
+			expected:  [][2]int64{{0, 0}, {0, 1}, {1, 1}, {2, 1}, {0, 3}, {0, 4}, {1, 4}, {2, 4}, {10, 10}}},
+		{name: "rotate 90",
+			transform: Transform{Region: region, Rotate: 90, ClearSource: true},
+			expected:  [][2]int64{{1, 0}, {0, 0}, {0, 1}, {0, 2}, {10, 10}}},
+		{name: "rotate 180",
+			transform: Transform{Region: region, Rotate: 180, ClearSource: true},
+			expected:  [][2]int64{{2, 1}, {2, 0}, {1, 0}, {0, 0}, {10, 10}}},
+		{name: "rotate 270",
+			transform: Transform{Region: region, Rotate: 270, ClearSource: true},
+			expected:  [][2]int64{{0, 2}, {1, 2}, {1, 1}, {1, 0}, {10, 10}}},
+		{name: "mirror horizontal",
+			transform: Transform{Region: region, Mirror: MirrorHorizontal, ClearSource: true},
+			expected:  [][2]int64{{2, 0}, {2, 1}, {1, 1}, {0, 1}, {10, 10}}},
+		{name: "mirror vertical",
+			transform: Transform{Region: region, Mirror: MirrorVertical, ClearSource: true},
+			expected:  [][2]int64{{0, 1}, {0, 0}, {1, 0}, {2, 0}, {10, 10}}},
+		{name: "whole board",
+			transform: Transform{DX: -1, DY: -1, ClearSource: true},
+			expected:  [][2]int64{{-1, -1}, {-1, 0}, {0, 0}, {1, 0}, {9, 9}}},
+	}
+
+	for _, testCase := range testTable {
+		game := NewGameOfLifeHandler(cells).gameOfLife
+		if err := game.ApplyTransform(testCase.transform); err != nil {
+			t.Errorf("%s: unexpected error %s", testCase.name, err)
+			continue
+		}
+		expected := NewGameOfLifeHandler(testCase.expected).gameOfLife
+		if !sameBoard(game, expected) {
+			t.Errorf("%s: expected %v but found %v", testCase.name, testCase.expected, game.getLiving())
+		}
+	}
+}
+
+func TestTransformOutOfBoard(t *testing.T) {
+	cells := [][2]int64{{math.MaxInt64 - 1, 0}, {math.MaxInt64, 0}, {0, 0}}
+	game := NewGameOfLifeHandler(cells).gameOfLife
+
+	err := game.ApplyTransform(Transform{DX: 1, ClearSource: true})
+	if err != ErrOutOfBoard {
+		t.Errorf("Expected %s but found %v", ErrOutOfBoard, err)
+	}
+	expected := NewGameOfLifeHandler(cells).gameOfLife
+	if !sameBoard(game, expected) {
+		t.Errorf("Expected the board not to change but found %v", game.getLiving())
+	}
+
+	// the whole board is as wide as int64 allows and is still mirrored
+	err = game.ApplyTransform(Transform{Mirror: MirrorHorizontal, ClearSource: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected = NewGameOfLifeHandler([][2]int64{{1, 0}, {0, 0}, {math.MaxInt64, 0}}).gameOfLife
+	if !sameBoard(game, expected) {
+		t.Errorf("Expected a mirrored board but found %v", game.getLiving())
+	}
+}
+
+func TestTransformRequests(t *testing.T) {
+	testSrv := setUpServer(blinker)
+	defer testSrv.Close()
+
+	testTable := []struct {
+		path, body string
+		status     int
+	}{
+		{path: "/region/rotate/", body: `{"rotate": 90}`, status: http.StatusNoContent},
+		{path: "/region/translate/", body: `{"region": {"min_x": 0, "min_y": 0, "max_x": 5, "max_y": 5}, "dx": 10}`,
+			status: http.StatusNoContent},
+		{path: "/region/mirror/", body: `{"mirror": "vertical"}`, status: http.StatusNoContent},
+		{path: "/region/copy/", body: `{"dy": 5, "clear_source": false}`, status: http.StatusNoContent},
+		{path: "/region/rotate/", body: `{"rotate": 45}`, status: http.StatusBadRequest},
+		{path: "/region/mirror/", body: `{}`, status: http.StatusBadRequest},
+		{path: "/region/copy/", body: `{"region": {"min_x": 5, "max_x": 0}}`, status: http.StatusBadRequest},
+		{path: "/region/translate/", body: `not json`, status: http.StatusBadRequest},
+		{path: "/region/translate/", body: `{"dx": 9223372036854775807}`, status: http.StatusBadRequest},
+	}
+	for _, testCase := range testTable {
+		resp, err := http.Post(buildUrl(testSrv.URL, testCase.path), "application/json",
+			bytes.NewBufferString(testCase.body))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != testCase.status {
+			t.Errorf("Expected status %d for %s %s but found %d", testCase.status,
+				testCase.path, testCase.body, resp.StatusCode)
+		}
+	}
+
+	// the blinker turned around the corner of its bounding box, moved right and copied down
+	expected := [][2]int64{{10, 1}, {10, 2}, {10, 3}, {10, 6}, {10, 7}, {10, 8}}
+	resp, err := http.Get(buildUrl(testSrv.URL, "/generation/"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+	generation := Generation{}
+	if err := json.NewDecoder(resp.Body).Decode(&generation); err != nil {
+		t.Fatalf("Error decoding json: %s", err)
+	}
+	found := NewGameOfLifeHandler(generation.Living).gameOfLife
+	if !sameBoard(found, NewGameOfLifeHandler(expected).gameOfLife) {
+		t.Errorf("Expected %v but found %v", expected, generation.Living)
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// The browser client - it uses only the json endpoints of the game
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// Serves the browser client at /ui/.
+// The files are public, the client asks for a token if the game needs one
+func uiHandler() http.Handler {
+	files, _ := fs.Sub(uiFiles, "ui")
+	return http.StripPrefix("/ui/", http.FileServer(http.FS(files)))
+}
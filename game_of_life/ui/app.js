@@ -0,0 +1,361 @@
+// Browser client of the game of life server.
+// It uses only the json endpoints, the board comes from the /events/ stream.
+// Each page is a session, so the others see its cursor and edits.
+// The coordinates are javascript numbers, so they are exact only up to 2^53.
+"use strict";
+
+const base = new URL("..", location.href);
+const canvas = document.getElementById("board");
+const context = canvas.getContext("2d");
+const statusLine = document.getElementById("status");
+const tokenInput = document.getElementById("token");
+const runButton = document.getElementById("run");
+const delayInput = document.getElementById("delay");
+
+// the living cells as "x,y" keys
+let living = new Set();
+let generation = 0;
+// the version of the board on the screen, the edits are made for it
+let version = "";
+// the cell at the top left corner of the canvas and the size of a cell in pixels
+const view = {x: -20, y: -20, zoom: 16};
+let running = false;
+let stream = null;
+let session = "";
+// the cursors of the other sessions by their ids
+const cursors = new Map();
+
+tokenInput.value = localStorage.getItem("token") || "";
+tokenInput.addEventListener("change", () => {
+  localStorage.setItem("token", tokenInput.value);
+  start();
+});
+
+function headers() {
+  const result = {"Content-Type": "application/json"};
+  if (tokenInput.value) {
+    result["Authorization"] = "Bearer " + tokenInput.value;
+  }
+  return result;
+}
+
+function showStatus(text, error) {
+  statusLine.textContent = text;
+  statusLine.className = error ? "error" : "";
+}
+
+function showBoard() {
+  showStatus("generation " + generation + ", " + living.size + " living");
+}
+
+// Sends a request to the game, throws with the answer of the server on failure
+async function api(method, path, body) {
+  const response = await fetch(new URL(path, base), {
+    method: method,
+    headers: headers(),
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+  if (!response.ok) {
+    const text = await response.text();
+    throw new Error(response.status + " " + text.trim());
+  }
+  return response;
+}
+
+function setBoard(board) {
+  generation = board.generation;
+  version = board.version;
+  living = new Set(board.living.map(cell => cell[0] + "," + cell[1]));
+  showBoard();
+  draw();
+}
+
+// Starts a session and listens to the board
+async function start() {
+  if (stream) {
+    stream.abort();
+  }
+  const controller = new AbortController();
+  stream = controller;
+  session = "";
+  cursors.clear();
+
+  while (stream === controller && !session) {
+    try {
+      const response = await api("POST", "sessions/");
+      session = (await response.json()).id;
+    } catch (error) {
+      showStatus(error.message, true);
+      await sleep(1000);
+    }
+  }
+  listen(controller);
+}
+
+// Reads the board and the messages of the other sessions from the /events/ stream,
+// connecting again if it breaks. The session ends with the stream, so a new one is started
+async function listen(controller) {
+  while (stream === controller) {
+    try {
+      const url = new URL("events/?session=" + encodeURIComponent(session), base);
+      const response = await fetch(url, {headers: headers(), signal: controller.signal});
+      if (response.status === 404) {
+        start();
+        return;
+      }
+      if (!response.ok) {
+        throw new Error(response.status + " " + (await response.text()).trim());
+      }
+      const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buffer = "";
+      for (;;) {
+        const {value, done} = await reader.read();
+        if (done) {
+          break;
+        }
+        buffer += value;
+        let end;
+        while ((end = buffer.indexOf("\n\n")) >= 0) {
+          readEvent(buffer.slice(0, end));
+          buffer = buffer.slice(end + 2);
+        }
+      }
+    } catch (error) {
+      if (controller.signal.aborted) {
+        return;
+      }
+      showStatus(error.message, true);
+    }
+    await sleep(1000);
+  }
+}
+
+function readEvent(text) {
+  let event = "message";
+  let data = null;
+  for (const line of text.split("\n")) {
+    if (line.startsWith("event: ")) {
+      event = line.slice(7);
+    } else if (line.startsWith("data: ")) {
+      data = JSON.parse(line.slice(6));
+    }
+  }
+  switch (event) {
+  case "generation":
+    setBoard(data);
+    break;
+  case "cursor":
+    cursors.set(data.id, {client: data.client, x: data.cursor.x, y: data.cursor.y});
+    draw();
+    break;
+  case "leave":
+    cursors.delete(data.id);
+    draw();
+    break;
+  }
+}
+
+function sleep(ms) {
+  return new Promise(resolve => setTimeout(resolve, ms));
+}
+
+function resize() {
+  canvas.width = canvas.clientWidth * devicePixelRatio;
+  canvas.height = canvas.clientHeight * devicePixelRatio;
+  draw();
+}
+
+function draw() {
+  const scale = view.zoom * devicePixelRatio;
+  context.clearRect(0, 0, canvas.width, canvas.height);
+
+  if (view.zoom >= 8) {
+    context.strokeStyle = "#eee";
+    context.beginPath();
+    const offsetX = (Math.ceil(view.x) - view.x) * scale;
+    const offsetY = (Math.ceil(view.y) - view.y) * scale;
+    for (let px = offsetX; px < canvas.width; px += scale) {
+      context.moveTo(Math.round(px) + 0.5, 0);
+      context.lineTo(Math.round(px) + 0.5, canvas.height);
+    }
+    for (let py = offsetY; py < canvas.height; py += scale) {
+      context.moveTo(0, Math.round(py) + 0.5);
+      context.lineTo(canvas.width, Math.round(py) + 0.5);
+    }
+    context.stroke();
+  }
+
+  context.fillStyle = "#222";
+  const size = Math.max(1, scale - (view.zoom >= 8 ? 1 : 0));
+  for (const key of living) {
+    const [x, y] = key.split(",").map(Number);
+    const px = (x - view.x) * scale;
+    const py = (y - view.y) * scale;
+    if (px + scale >= 0 && py + scale >= 0 && px < canvas.width && py < canvas.height) {
+      context.fillRect(Math.round(px) + 1, Math.round(py) + 1, size, size);
+    }
+  }
+
+  context.font = 12 * devicePixelRatio + "px sans-serif";
+  for (const cursor of cursors.values()) {
+    const px = (cursor.x - view.x) * scale;
+    const py = (cursor.y - view.y) * scale;
+    context.strokeStyle = context.fillStyle = color(cursor.client);
+    context.lineWidth = 2 * devicePixelRatio;
+    context.strokeRect(px, py, Math.max(scale, 4), Math.max(scale, 4));
+    context.fillText(cursor.client, px, py - 4 * devicePixelRatio);
+    context.lineWidth = 1;
+  }
+}
+
+// Returns the same color for the same client
+function color(client) {
+  let hash = 0;
+  for (const c of client) {
+    hash = (hash * 31 + c.charCodeAt(0)) % 360;
+  }
+  return "hsl(" + hash + ", 70%, 45%)";
+}
+
+// Returns the cell under the mouse
+function cellAt(event) {
+  const rect = canvas.getBoundingClientRect();
+  return [
+    Math.floor(view.x + (event.clientX - rect.left) / view.zoom),
+    Math.floor(view.y + (event.clientY - rect.top) / view.zoom),
+  ];
+}
+
+// The edit is made for the board on the screen,
+// it is refused if the board has changed meanwhile
+async function toggle(cell) {
+  const alive = living.has(cell[0] + "," + cell[1]);
+  const query = "?session=" + encodeURIComponent(session) + "&version=" + encodeURIComponent(version);
+  try {
+    await api("POST", (alive ? "cells/remove/" : "cells/") + query, [{x: cell[0], y: cell[1]}]);
+  } catch (error) {
+    showStatus(error.message.startsWith("409") ? "The board has changed, try again" : error.message, true);
+  }
+}
+
+// Sends the cell under the mouse at most every 100ms
+let cursorSent = 0;
+let cursorTimer = null;
+function sendCursor(cell) {
+  clearTimeout(cursorTimer);
+  const wait = cursorSent + 100 - Date.now();
+  if (wait > 0) {
+    cursorTimer = setTimeout(() => sendCursor(cell), wait);
+    return;
+  }
+  cursorSent = Date.now();
+  if (session) {
+    api("POST", "sessions/cursor/?session=" + encodeURIComponent(session), {x: cell[0], y: cell[1]})
+      .catch(() => {});
+  }
+}
+
+// a click changes a cell, a drag moves the view
+let press = null;
+canvas.addEventListener("mousedown", event => {
+  press = {x: event.clientX, y: event.clientY, viewX: view.x, viewY: view.y, dragged: false};
+});
+canvas.addEventListener("mousemove", event => {
+  if (!press) {
+    sendCursor(cellAt(event));
+    return;
+  }
+  const dx = event.clientX - press.x;
+  const dy = event.clientY - press.y;
+  if (Math.abs(dx) + Math.abs(dy) > 3) {
+    press.dragged = true;
+  }
+  if (press.dragged) {
+    view.x = press.viewX - dx / view.zoom;
+    view.y = press.viewY - dy / view.zoom;
+    draw();
+  }
+});
+canvas.addEventListener("mouseup", event => {
+  if (press && !press.dragged) {
+    toggle(cellAt(event));
+  }
+  press = null;
+});
+canvas.addEventListener("mouseleave", () => {
+  press = null;
+});
+
+// zooms around the mouse, so the cell under it stays there
+canvas.addEventListener("wheel", event => {
+  event.preventDefault();
+  const rect = canvas.getBoundingClientRect();
+  const mouseX = event.clientX - rect.left;
+  const mouseY = event.clientY - rect.top;
+  const cellX = view.x + mouseX / view.zoom;
+  const cellY = view.y + mouseY / view.zoom;
+  const zoom = view.zoom * (event.deltaY < 0 ? 1.25 : 0.8);
+  view.zoom = Math.min(64, Math.max(0.25, zoom));
+  view.x = cellX - mouseX / view.zoom;
+  view.y = cellY - mouseY / view.zoom;
+  draw();
+}, {passive: false});
+
+// Moves and zooms the view to show all the living cells
+function center() {
+  if (living.size === 0) {
+    return;
+  }
+  let minX = Infinity, minY = Infinity, maxX = -Infinity, maxY = -Infinity;
+  for (const key of living) {
+    const [x, y] = key.split(",").map(Number);
+    minX = Math.min(minX, x);
+    minY = Math.min(minY, y);
+    maxX = Math.max(maxX, x);
+    maxY = Math.max(maxY, y);
+  }
+  const width = maxX - minX + 3;
+  const height = maxY - minY + 3;
+  view.zoom = Math.min(64, Math.max(0.25, Math.min(canvas.clientWidth / width, canvas.clientHeight / height)));
+  view.x = minX - 1 - (canvas.clientWidth / view.zoom - width) / 2;
+  view.y = minY - 1 - (canvas.clientHeight / view.zoom - height) / 2;
+  draw();
+}
+
+async function step() {
+  try {
+    await api("POST", "generation/evolve/");
+    return true;
+  } catch (error) {
+    showStatus(error.message, true);
+    return false;
+  }
+}
+
+async function run() {
+  running = !running;
+  runButton.textContent = running ? "Stop" : "Run";
+  while (running) {
+    if (!await step()) {
+      running = false;
+      runButton.textContent = "Run";
+      return;
+    }
+    await sleep(Math.max(0, Number(delayInput.value) || 0));
+  }
+}
+
+document.getElementById("step").addEventListener("click", step);
+runButton.addEventListener("click", run);
+document.getElementById("center").addEventListener("click", center);
+document.getElementById("reset").addEventListener("click", async () => {
+  try {
+    await api("POST", "reset/");
+  } catch (error) {
+    showStatus(error.message, true);
+  }
+});
+
+window.addEventListener("resize", resize);
+resize();
+start();
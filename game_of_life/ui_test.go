@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUI(t *testing.T) {
+	testSrv := setUpServer(nil)
+	defer testSrv.Close()
+
+	testTable := []struct {
+		path        string
+		contentType string
+		contains    string
+	}{
+		{path: "/ui/", contentType: "text/html", contains: "<canvas"},
+		{path: "/ui/app.js", contentType: "javascript", contains: "events/"},
+		{path: "/ui/style.css", contentType: "text/css", contains: "#board"},
+	}
+	for _, testCase := range testTable {
+		resp, err := http.Get(buildUrl(testSrv.URL, testCase.path))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200 for %s but found %d", testCase.path, resp.StatusCode)
+		}
+		if contentType := resp.Header.Get("Content-Type"); !strings.Contains(contentType, testCase.contentType) {
+			t.Errorf("Expected %s for %s but found %s", testCase.contentType, testCase.path, contentType)
+		}
+		if !strings.Contains(string(body), testCase.contains) {
+			t.Errorf("Expected %s to contain %s", testCase.path, testCase.contains)
+		}
+	}
+}
+
+func TestRemoveCells(t *testing.T) {
+	testSrv := setUpServer([][2]int64{{0, 1}, {1, 1}, {2, 1}})
+	defer testSrv.Close()
+
+	resp, err := http.Post(buildUrl(testSrv.URL, "/cells/remove/"), "application/json",
+		bytes.NewBufferString(`[{"x": 1, "y": 1}, {"x": 5, "y": 5}]`))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status 204 but found %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(buildUrl(testSrv.URL, "/generation/"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+	generation := Generation{}
+	if err := json.NewDecoder(resp.Body).Decode(&generation); err != nil {
+		t.Fatalf("Error decoding json: %s", err)
+	}
+	expected := NewGameOfLifeHandler([][2]int64{{0, 1}, {2, 1}}).gameOfLife
+	got := NewGameOfLifeHandler(generation.Living).gameOfLife
+	if !sameBoard(expected, got) {
+		t.Errorf("Expected %v but found %v", expected.getLiving(), generation.Living)
+	}
+
+	resp, err = http.Post(buildUrl(testSrv.URL, "/cells/remove/"), "application/json",
+		bytes.NewBufferString(`{"x": 1}`))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 but found %d", resp.StatusCode)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	testSrv := setUpServer([][2]int64{{0, 1}, {1, 1}, {2, 1}})
+	defer testSrv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", buildUrl(testSrv.URL, "/events/"), nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Expected text/event-stream but found %s", contentType)
+	}
+	reader := bufio.NewReader(resp.Body)
+
+	first := readGenerationEvent(t, reader)
+	if first.Generation != 0 || len(first.Living) != 3 {
+		t.Errorf("Expected the starting board but found %v", first)
+	}
+
+	resp2, err := http.Post(buildUrl(testSrv.URL, "/generation/evolve/"), "", nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp2.Body.Close()
+
+	next := readGenerationEvent(t, reader)
+	if next.Generation != 1 || len(next.Living) != 3 {
+		t.Errorf("Expected the first generation but found %v", next)
+	}
+}
+
+// Reads the next generation event from a server-sent event stream
+func readGenerationEvent(t *testing.T, reader *bufio.Reader) Generation {
+	generation := Generation{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if strings.HasPrefix(line, "data: ") {
+			if err := json.Unmarshal([]byte(line[len("data: "):]), &generation); err != nil {
+				t.Fatalf("Error decoding json: %s", err)
+			}
+		}
+		if line == "\n" {
+			return generation
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package librarian
+
+import "fmt"
+
+// The type of the request with many borrow and return requests done at once
+const Batch = QueuePosition + 1
+
+// The type for the requests done all together or not at all
+type CoolBatchRequest struct {
+	requests []LibraryRequest
+}
+
+// Creates a request which borrows and returns all the books of the requests,
+// or none of them if one fails. Only BorrowBook and ReturnBook requests can be in a batch
+func NewBatchRequest(requests ...LibraryRequest) *CoolBatchRequest {
+	return &CoolBatchRequest{requests: requests}
+}
+
+// Return the type of the request
+func (request *CoolBatchRequest) GetType() int {
+	return Batch
+}
+
+// The batch is not about a single book
+func (request *CoolBatchRequest) GetISBN() string {
+	return ""
+}
+
+// Returns the requests in the batch
+func (request *CoolBatchRequest) GetRequests() []LibraryRequest {
+	return request.requests
+}
+
+// The type for the responses to batch requests
+type CoolBatchResponse struct {
+	responses []LibraryResponse
+	err       error
+}
+
+// Returns the book of the first response
+func (response *CoolBatchResponse) GetBook() (fmt.Stringer, error) {
+	if response.err != nil {
+		return nil, response.err
+	}
+	if len(response.responses) == 0 {
+		return nil, &LibraryError{Kind: ErrEmptyResponse}
+	}
+	return response.responses[0].GetBook()
+}
+
+// A batch is not about the availability of a single book
+func (response *CoolBatchResponse) GetAvailability() (available int, registered int) {
+	return 0, 0
+}
+
+// Returns the responses in the order of the requests,
+// or the error of the first failed request
+func (response *CoolBatchResponse) GetResponses() ([]LibraryResponse, error) {
+	return response.responses, response.err
+}
+
+// Does all the requests of the batch, or none of them if one fails
+func (librarian *Librarian) batch(message LibraryRequest) LibraryResponse {
+	library := librarian.library
+	batch, ok := message.(LibraryBatchRequest)
+	if !ok {
+		return &CoolBatchResponse{err: library.fail(LibraryError{Kind: ErrInvalidRequest})}
+	}
+
+	library.mutex.Lock()
+	defer library.mutex.Unlock()
+	responses := make([]LibraryResponse, 0, len(batch.GetRequests()))
+	err := library.transaction(func(saved *snapshot) error {
+		for _, request := range batch.GetRequests() {
+			isbn, member := library.resolve(request.GetISBN()), memberID(request)
+			saved.save(library, isbn, member)
+
+			var response LibraryResponse
+			switch request.GetType() {
+			case BorrowBook:
+				response = library.checkOut(isbn, member)
+			case ReturnBook:
+				response = library.checkIn(isbn, member)
+			default:
+				response = &CoolLibraryResponse{err: library.fail(LibraryError{Kind: ErrInvalidRequest})}
+			}
+			if _, err := response.GetBook(); err != nil {
+				return err
+			}
+			// each response has the counts after its own request
+			responses = append(responses, detach(response))
+		}
+		return nil
+	})
+	if err != nil {
+		return &CoolBatchResponse{err: err}
+	}
+	return &CoolBatchResponse{responses: responses}
+}
@@ -0,0 +1,172 @@
+package librarian
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"testing/quick"
+)
+
+func TestBatch(t *testing.T) {
+	library := NewFancyLibrary(1)
+	for _, book := range catalogueBooks {
+		library.AddBookJSON([]byte(book))
+	}
+	library.AddMember("alice", "Alice")
+
+	request, response := library.Hello()
+	defer close(request)
+	expectAvailable := func(expected [3]int) {
+		t.Helper()
+		found := [3]int{}
+		for i, isbn := range []string{"9781617293092", "0954540018", "9780134190440"} {
+			found[i], _ = ask(request, response, &CoolLibraryRequest{isbn, GetAvailability}).GetAvailability()
+		}
+		if found != expected {
+			t.Errorf("Expected available copies %v but found %v", expected, found)
+		}
+	}
+
+	message := ask(request, response, NewBatchRequest(
+		NewMemberRequest(BorrowBook, "9781617293092", "alice"),
+		&CoolLibraryRequest{"0954540018", BorrowBook}))
+	responses, err := message.(LibraryBatchResponse).GetResponses()
+	if err != nil || len(responses) != 2 {
+		t.Fatalf("Expected 2 responses but found %v, %v", responses, err)
+	}
+	if _, ok := responses[0].(LibraryLoanResponse); !ok {
+		t.Errorf("Expected the loan of alice but found %T", responses[0])
+	}
+	expectAvailable([3]int{0, 0, 1})
+
+	// the third book is not there, so the first two stay
+	message = ask(request, response, NewBatchRequest(
+		NewMemberRequest(ReturnBook, "9781617293092", "alice"),
+		&CoolLibraryRequest{"9780134190440", BorrowBook},
+		&CoolLibraryRequest{"0954540018", BorrowBook}))
+	expectError(t, message, "Няма наличност на книга 0954540018")
+	expectAvailable([3]int{0, 0, 1})
+	if loans := loansOf(t, ask(request, response, NewMemberRequest(ListLoans, "", "alice"))); len(loans) != 1 {
+		t.Errorf("Expected the loan of alice to stay but found %v", loans)
+	}
+
+	_, err = ask(request, response, NewBatchRequest(
+		&CoolLibraryRequest{"9780134190440", BorrowBook},
+		NewCatalogueRequest(ListBooks, ""))).GetBook()
+	if !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Expected an invalid request but found %v", err)
+	}
+	expectAvailable([3]int{0, 0, 1})
+}
+
+func TestReturnWithoutChanges(t *testing.T) {
+	library := NewFancyLibrary(1)
+	library.AddBookJSON([]byte(catalogueBooks[0]))
+	request, response := library.Hello()
+	defer close(request)
+
+	for i := 0; i < 3; i++ {
+		expectError(t, ask(request, response, &CoolLibraryRequest{"9781617293092", ReturnBook}),
+			"Всички копия са налични 9781617293092")
+	}
+	available, registered := ask(request, response, &CoolLibraryRequest{"9781617293092", GetAvailability}).GetAvailability()
+	if available != 1 || registered != 1 {
+		t.Errorf("Expected 1 of 1 copies available but found %d of %d", available, registered)
+	}
+}
+
+// Returns an error if the counts of a book do not match its copies, loans and reservations
+// Has to be called while holding the mutex
+func checkInvariants(library *FancyLibrary) error {
+	for isbn, book := range library.books {
+		lent, held := 0, 0
+		for _, member := range library.members {
+			for _, loan := range member.loans {
+				if loan.Book == book {
+					lent++
+				}
+			}
+		}
+		for _, reservation := range library.holds[isbn] {
+			if !reservation.HeldUntil.IsZero() {
+				held++
+			}
+		}
+		if book.availableCount < 0 || book.held != held || book.lent != lent ||
+			book.availableCount+book.held+book.lent > book.registeredCount ||
+			book.registeredCount > library.limitOf(isbn) {
+			return fmt.Errorf("%s has %d available, %d held, %d lent and %d registered but %d held and %d lent were found",
+				isbn, book.availableCount, book.held, book.lent, book.registeredCount, held, lent)
+		}
+	}
+	return nil
+}
+
+func TestAvailabilityInvariant(t *testing.T) {
+	isbns := []string{"9781617293092", "0954540018", "9780134190440"}
+	members := []string{"", "alice", "bob"}
+	// Makes a request from a random number
+	requestOf := func(n uint16) LibraryRequest {
+		isbn, member := isbns[int(n/6)%len(isbns)], members[int(n/18)%len(members)]
+		other := isbns[(int(n/6)+1)%len(isbns)]
+		switch n % 6 {
+		case 0:
+			return NewMemberRequest(BorrowBook, isbn, member)
+		case 1:
+			return NewMemberRequest(ReturnBook, isbn, member)
+		case 2:
+			return NewMemberRequest(Reserve, isbn, member)
+		case 3:
+			return NewMemberRequest(CancelReservation, isbn, member)
+		case 4:
+			return NewBatchRequest(NewMemberRequest(BorrowBook, isbn, member),
+				NewMemberRequest(BorrowBook, other, member))
+		default:
+			return NewBatchRequest(NewMemberRequest(ReturnBook, isbn, member),
+				NewMemberRequest(ReturnBook, other, member))
+		}
+	}
+
+	property := func(workloads [3][]uint16) bool {
+		library := NewFancyLibrary(len(workloads))
+		for _, book := range catalogueBooks {
+			library.AddBookJSON([]byte(book))
+			library.AddBookJSON([]byte(book))
+		}
+		library.AddMember("alice", "Alice")
+		library.AddMember("bob", "Bob")
+
+		var wait sync.WaitGroup
+		failures := make(chan error, 100)
+		for _, workload := range workloads {
+			wait.Add(1)
+			go func(workload []uint16) {
+				defer wait.Done()
+				request, response := library.Hello()
+				defer close(request)
+				for _, n := range workload {
+					request <- requestOf(n)
+					<-response
+					library.mutex.Lock()
+					err := checkInvariants(library)
+					library.mutex.Unlock()
+					if err != nil {
+						failures <- err
+						return
+					}
+				}
+			}(workload)
+		}
+		wait.Wait()
+		close(failures)
+		for err := range failures {
+			t.Error(err)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 50}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,155 @@
+package librarian
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Types of the requests for the whole catalogue
+const (
+	// all the books in the library
+	ListBooks = iota + GetAvailability + 1
+	// the books with the query in their title
+	SearchByTitle
+	// the books with the query in the name of their author
+	SearchByAuthor
+	// the books without available copies
+	ListUnavailable
+)
+
+// The books of the genre, the case of the letters does not matter
+const SearchByGenre = Batch + 1
+
+// The type for the requests about the catalogue
+type CoolCatalogueRequest struct {
+	query       string
+	requestType int
+}
+
+// Creates a request about the catalogue.
+// The query is used only by SearchByTitle, SearchByAuthor and SearchByGenre
+func NewCatalogueRequest(requestType int, query string) *CoolCatalogueRequest {
+	return &CoolCatalogueRequest{query: query, requestType: requestType}
+}
+
+// Return the type of the request
+func (request *CoolCatalogueRequest) GetType() int {
+	return request.requestType
+}
+
+// The catalogue requests are not about a single book
+func (request *CoolCatalogueRequest) GetISBN() string {
+	return ""
+}
+
+// Return the text to search for
+func (request *CoolCatalogueRequest) GetQuery() string {
+	return request.query
+}
+
+// The type for the responses with many books
+type CoolBooksResponse struct {
+	books []*Book
+	err   error
+}
+
+// Returns the first of the books, the catalogue requests have many.
+// Returns an error if the request failed or no book was found
+func (response *CoolBooksResponse) GetBook() (fmt.Stringer, error) {
+	if response.err != nil {
+		return nil, response.err
+	}
+	if len(response.books) == 0 {
+		return nil, &LibraryError{Kind: ErrEmptyResponse}
+	}
+	return response.books[0], nil
+}
+
+// Returns the copies of all the books in the response
+func (response *CoolBooksResponse) GetAvailability() (available int, registered int) {
+	for _, book := range response.books {
+		available += book.availableCount
+		registered += book.registeredCount
+	}
+	return available, registered
+}
+
+// Returns the books ordered by title
+func (response *CoolBooksResponse) GetBooks() ([]fmt.Stringer, error) {
+	if response.err != nil {
+		return nil, response.err
+	}
+	books := make([]fmt.Stringer, len(response.books))
+	for i, book := range response.books {
+		books[i] = book
+	}
+	return books, nil
+}
+
+// Returns copies of the books for which matches is true, ordered by title and isbn.
+// The copies do not change after the response is sent
+func (librarian *Librarian) findBooks(matches func(book *Book) bool) CoolBooksResponse {
+	librarian.library.mutex.Lock()
+	defer librarian.library.mutex.Unlock()
+	if err := librarian.library.refreshAllHolds(); err != nil {
+		return CoolBooksResponse{err: err}
+	}
+	found := make([]*Book, 0)
+	for _, book := range librarian.library.books {
+		if matches(book) {
+			copied := *book
+			found = append(found, &copied)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Title != found[j].Title {
+			return found[i].Title < found[j].Title
+		}
+		return found[i].ISBN < found[j].ISBN
+	})
+	return CoolBooksResponse{books: found}
+}
+
+// Answers the requests about the whole catalogue
+func (librarian *Librarian) searchCatalogue(request LibraryRequest) CoolBooksResponse {
+	var query string
+	search, ok := request.(LibrarySearchRequest)
+	if ok {
+		query = strings.ToLower(search.GetQuery())
+	}
+
+	switch request.GetType() {
+	case ListBooks:
+		return librarian.findBooks(func(book *Book) bool {
+			return true
+		})
+	case SearchByTitle:
+		if !ok {
+			break
+		}
+		return librarian.findBooks(func(book *Book) bool {
+			return strings.Contains(strings.ToLower(book.Title), query)
+		})
+	case SearchByAuthor:
+		if !ok {
+			break
+		}
+		return librarian.findBooks(func(book *Book) bool {
+			name := book.Author.FirstName + " " + book.Author.LastName
+			return strings.Contains(strings.ToLower(name), query)
+		})
+	case ListUnavailable:
+		return librarian.findBooks(func(book *Book) bool {
+			return book.availableCount <= 0
+		})
+	case SearchByGenre:
+		if !ok {
+			break
+		}
+		return librarian.findBooks(func(book *Book) bool {
+			return strings.ToLower(book.Genre) == query
+		})
+	}
+	return CoolBooksResponse{err: librarian.library.fail(LibraryError{Kind: ErrInvalidRequest})}
+}
@@ -0,0 +1,141 @@
+package librarian
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+var catalogueBooks = []string{
+	`{"isbn": "9781617293092", "title": "Learn Go",
+	  "author": {"first_name": "Nathan", "last_name": "Youngman"}}`,
+	`{"isbn": "0954540018", "title": "Who Said the Race is Over?",
+	  "author": {"first_name": "Anno", "last_name": "Birkin"}}`,
+	`{"isbn": "9780134190440", "title": "The Go Programming Language",
+	  "author": {"first_name": "Alan", "last_name": "Donovan"}}`,
+}
+
+// Creates a library with one copy of each catalogue book
+func newCatalogueLibrary(t *testing.T, librarians int) Library {
+	library := NewLibrary(librarians)
+	for _, book := range catalogueBooks {
+		if _, err := library.AddBookJSON([]byte(book)); err != nil {
+			t.Fatalf("An error occured while parsing json %s", err.Error())
+		}
+	}
+	return library
+}
+
+// Returns the string of each book in the response
+func bookStrings(t *testing.T, message LibraryResponse) []string {
+	response, ok := message.(LibraryBooksResponse)
+	if !ok {
+		t.Fatalf("Expected a response with many books but found %T", message)
+	}
+	books, err := response.GetBooks()
+	if err != nil {
+		t.Fatalf("There must not be an error but found %s", err.Error())
+	}
+	found := make([]string, len(books))
+	for i, book := range books {
+		found[i] = book.String()
+	}
+	return found
+}
+
+func TestCatalogueRequests(t *testing.T) {
+	library := newCatalogueLibrary(t, 2)
+	request, response := library.Hello()
+	defer close(request)
+
+	request <- &CoolLibraryRequest{"0954540018", BorrowBook}
+	<-response
+
+	testTable := []struct {
+		request  LibraryRequest
+		expected []string
+	}{
+		{request: NewCatalogueRequest(ListBooks, ""), expected: []string{
+			"[9781617293092] Learn Go от Nathan Youngman",
+			"[9780134190440] The Go Programming Language от Alan Donovan",
+			"[0954540018] Who Said the Race is Over? от Anno Birkin",
+		}},
+		{request: NewCatalogueRequest(SearchByTitle, "GO"), expected: []string{
+			"[9781617293092] Learn Go от Nathan Youngman",
+			"[9780134190440] The Go Programming Language от Alan Donovan",
+		}},
+		{request: NewCatalogueRequest(SearchByAuthor, "anno birk"), expected: []string{
+			"[0954540018] Who Said the Race is Over? от Anno Birkin",
+		}},
+		{request: NewCatalogueRequest(SearchByTitle, "Rust"), expected: []string{}},
+		{request: NewCatalogueRequest(ListUnavailable, ""), expected: []string{
+			"[0954540018] Who Said the Race is Over? от Anno Birkin",
+		}},
+	}
+	for _, testCase := range testTable {
+		request <- testCase.request
+		found := bookStrings(t, <-response)
+		if fmt.Sprint(found) != fmt.Sprint(testCase.expected) {
+			t.Errorf("Expected\n---\n%v\n---\nbut found\n---\n%v\n---\n", testCase.expected, found)
+		}
+	}
+
+	// a search needs a query
+	request <- &CoolLibraryRequest{"", SearchByTitle}
+	_, err := (<-response).GetBook()
+	if err == nil || err.Error() != "Невалидна заявка" {
+		t.Errorf("Expected error Невалидна заявка but found %v", err)
+	}
+}
+
+func TestCatalogueConcurrentLibrarians(t *testing.T) {
+	library := newCatalogueLibrary(t, 4)
+	var wait sync.WaitGroup
+	errors := make(chan string, 100)
+
+	// two librarians lend and take back books
+	for _, isbn := range []string{"0954540018", "9781617293092"} {
+		wait.Add(1)
+		go func(isbn string) {
+			defer wait.Done()
+			request, response := library.Hello()
+			defer close(request)
+			for i := 0; i < 200; i++ {
+				request <- &CoolLibraryRequest{isbn, BorrowBook}
+				<-response
+				request <- &CoolLibraryRequest{isbn, ReturnBook}
+				<-response
+			}
+		}(isbn)
+	}
+
+	// and two others look at the catalogue meanwhile
+	for i := 0; i < 2; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			request, response := library.Hello()
+			defer close(request)
+			for i := 0; i < 200; i++ {
+				request <- NewCatalogueRequest(ListBooks, "")
+				books, err := (<-response).(LibraryBooksResponse).GetBooks()
+				if err != nil || len(books) != 3 {
+					errors <- fmt.Sprintf("Expected 3 books but found %v, %v", books, err)
+					return
+				}
+				request <- NewCatalogueRequest(ListUnavailable, "")
+				books, err = (<-response).(LibraryBooksResponse).GetBooks()
+				if err != nil || len(books) > 2 {
+					errors <- fmt.Sprintf("Expected at most 2 unavailable books but found %v, %v", books, err)
+					return
+				}
+			}
+		}()
+	}
+
+	wait.Wait()
+	close(errors)
+	for err := range errors {
+		t.Error(err)
+	}
+}
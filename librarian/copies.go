@@ -0,0 +1,160 @@
+package librarian
+
+// The most copies of a book if there is no other limit
+const DefaultCopyLimit = 4
+
+// Sets the most copies of each book in the library
+func WithCopyLimit(limit int) Option {
+	return func(library *FancyLibrary) {
+		library.copyLimit = limit
+	}
+}
+
+// Sets the most copies of the book with the isbn,
+// instead of the limit for all the books. The isbn can be in any form
+func WithBookCopyLimit(isbn string, limit int) Option {
+	return func(library *FancyLibrary) {
+		// the books are looked up with their normalised isbn
+		if normalized, ok := normalizeISBN(isbn); ok {
+			isbn = normalized
+		}
+		library.copyLimits[isbn] = limit
+	}
+}
+
+// Returns the most copies the library can have of the book
+// Has to be called while holding the mutex
+func (library *FancyLibrary) limitOf(isbn string) int {
+	if limit, ok := library.copyLimits[isbn]; ok {
+		return limit
+	}
+	// the limit can be given with any form of the isbn
+	for _, form := range isbnForms(isbn) {
+		if limit, ok := library.copyLimits[form]; ok {
+			return limit
+		}
+	}
+	return library.copyLimit
+}
+
+// Adds a copy of a book to the library, as addBook (LibraryAddRequest)
+const AddBook = Renew + 1
+
+// The type for the requests adding a copy of a book
+type CoolAddRequest struct {
+	book Book
+}
+
+// Creates a request adding a copy of the book.
+// The book is copied, so it can be changed after the request is made
+func NewAddRequest(book *Book) *CoolAddRequest {
+	return &CoolAddRequest{book: *book}
+}
+
+// Return the type of the request
+func (request *CoolAddRequest) GetType() int {
+	return AddBook
+}
+
+// Return the isbn of the book as it is given
+func (request *CoolAddRequest) GetISBN() string {
+	return request.book.ISBN
+}
+
+// Returns a copy of the book to add
+func (request *CoolAddRequest) GetNewBook() *Book {
+	book := request.book
+	return &book
+}
+
+// Adds the book of the request.
+// The response has a copy of the book in the library with its counts
+func (librarian *Librarian) add(message LibraryRequest) LibraryResponse {
+	request, ok := message.(LibraryAddRequest)
+	if !ok {
+		return &CoolLibraryResponse{err: librarian.library.fail(LibraryError{Kind: ErrInvalidRequest})}
+	}
+	_, added, err := librarian.library.add(request.GetNewBook())
+	return &CoolLibraryResponse{book: added, err: err}
+}
+
+// Removes a copy of the book from the library.
+// A copy in the library is removed at once. If all copies are taken
+// one of them is removed when it is returned.
+// Returns the count of the copies which stay in the library
+func (library *FancyLibrary) RemoveCopy(isbn string) (int, error) {
+	library.mutex.Lock()
+	defer library.mutex.Unlock()
+	isbn = library.resolve(isbn)
+	var left int
+	err := library.transaction(func(saved *snapshot) error {
+		saved.save(library, isbn, "")
+		var err error
+		left, err = library.withdraw(isbn, 1)
+		return err
+	})
+	return left, err
+}
+
+// Removes all the copies of the book from the library.
+// The taken copies are removed when they are returned and then the book is gone.
+// Returns the count of the copies which are still taken
+func (library *FancyLibrary) WithdrawBook(isbn string) (int, error) {
+	library.mutex.Lock()
+	defer library.mutex.Unlock()
+	isbn = library.resolve(isbn)
+	book, ok := library.books[isbn]
+	if !ok {
+		return 0, library.fail(LibraryError{Kind: ErrUnknownBook, ISBN: isbn})
+	}
+	var taken int
+	err := library.transaction(func(saved *snapshot) error {
+		saved.save(library, isbn, "")
+		_, err := library.withdraw(isbn, book.registeredCount-book.withdrawn)
+		taken = book.withdrawn
+		return err
+	})
+	return taken, err
+}
+
+// Removes count copies of the book - the available ones first
+// Has to be called while holding the mutex
+func (library *FancyLibrary) withdraw(isbn string, count int) (int, error) {
+	book, ok := library.books[isbn]
+	if !ok {
+		return 0, library.fail(LibraryError{Kind: ErrUnknownBook, ISBN: isbn})
+	}
+	if count > book.registeredCount-book.withdrawn {
+		return book.registeredCount - book.withdrawn, library.fail(LibraryError{Kind: ErrNothingToWithdraw, ISBN: isbn})
+	}
+
+	removed := count
+	if removed > book.availableCount {
+		removed = book.availableCount
+	}
+	book.availableCount -= removed
+	book.registeredCount -= removed
+	book.withdrawn += count - removed
+	library.forgetWithdrawn(book)
+	return book.registeredCount - book.withdrawn, nil
+}
+
+// Removes a returned copy if the book is being withdrawn
+// Has to be called while holding the mutex
+func (library *FancyLibrary) removeReturned(book *Book) {
+	if book.withdrawn > 0 && book.availableCount > 0 {
+		book.withdrawn -= 1
+		book.availableCount -= 1
+		book.registeredCount -= 1
+		library.forgetWithdrawn(book)
+	}
+}
+
+// Removes the book from the catalogue when its last copy is removed
+// Has to be called while holding the mutex
+func (library *FancyLibrary) forgetWithdrawn(book *Book) {
+	if book.registeredCount == 0 {
+		delete(library.books, book.ISBN)
+		delete(library.holds, book.ISBN)
+	}
+}
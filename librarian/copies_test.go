@@ -0,0 +1,131 @@
+package librarian
+
+import "testing"
+
+func TestCopyLimits(t *testing.T) {
+	library := NewFancyLibrary(1, WithCopyLimit(2), WithBookCopyLimit("0954540018", 5))
+
+	testTable := []struct {
+		book     string
+		times    int
+		expected string
+	}{
+		{book: catalogueBooks[0], times: 2, expected: ""},
+		{book: catalogueBooks[0], times: 1, expected: "Има 2 копия на книга 9781617293092"},
+		{book: catalogueBooks[1], times: 5, expected: ""},
+		{book: catalogueBooks[1], times: 1, expected: "Има 5 копия на книга 0954540018"},
+	}
+	for _, testCase := range testTable {
+		for i := 0; i < testCase.times; i++ {
+			_, err := library.AddBookJSON([]byte(testCase.book))
+			found := ""
+			if err != nil {
+				found = err.Error()
+			}
+			if found != testCase.expected {
+				t.Errorf("Expected error %q but found %q", testCase.expected, found)
+			}
+		}
+	}
+
+	// no copies at all
+	library = NewFancyLibrary(1, WithBookCopyLimit("0954540018", 0))
+	if _, err := library.AddBookJSON([]byte(catalogueBooks[1])); err == nil {
+		t.Errorf("Expected an error for a book without copies")
+	}
+	if _, ok := library.books["0954540018"]; ok {
+		t.Errorf("Expected the book not to be added")
+	}
+}
+
+func TestBookCopyLimitForms(t *testing.T) {
+	testTable := []struct {
+		limitISBN, bookISBN string
+	}{
+		{limitISBN: "0-9545-4001-8", bookISBN: "0954540018"},
+		{limitISBN: "0954540018", bookISBN: "978-0-9545400-1-2"},
+		{limitISBN: "978 0954540012", bookISBN: "0954540018"},
+		{limitISBN: "0-8044-2957-x", bookISBN: "080442957X"},
+	}
+	for _, testCase := range testTable {
+		library := NewFancyLibrary(1, WithBookCopyLimit(testCase.limitISBN, 1))
+		book := `{"isbn": "` + testCase.bookISBN + `", "title": "Limited"}`
+		if _, err := library.AddBookJSON([]byte(book)); err != nil {
+			t.Fatalf("There must not be an error for %s but found %s", testCase.bookISBN, err.Error())
+		}
+		if _, err := library.AddBookJSON([]byte(book)); err == nil {
+			t.Errorf("Expected the limit for %s to be used for %s", testCase.limitISBN, testCase.bookISBN)
+		}
+	}
+}
+
+func TestWithdrawLentCopies(t *testing.T) {
+	library := NewFancyLibrary(1)
+	for i := 0; i < 3; i++ {
+		library.AddBookJSON([]byte(catalogueBooks[0]))
+	}
+	library.AddMember("alice", "Alice")
+
+	request, response := library.Hello()
+	defer close(request)
+	expectAvailability := func(available, registered int) {
+		t.Helper()
+		message := ask(request, response, &CoolLibraryRequest{"9781617293092", GetAvailability})
+		if foundAvailable, foundRegistered := message.GetAvailability(); foundAvailable != available ||
+			foundRegistered != registered {
+			t.Errorf("Expected %d of %d copies available but found %d of %d",
+				available, registered, foundAvailable, foundRegistered)
+		}
+	}
+
+	expectError(t, ask(request, response, NewMemberRequest(BorrowBook, "9781617293092", "alice")), "")
+	expectError(t, ask(request, response, &CoolLibraryRequest{"9781617293092", BorrowBook}), "")
+
+	// the copy in the library goes at once
+	if left, err := library.RemoveCopy("9781617293092"); err != nil || left != 2 {
+		t.Errorf("Expected 2 copies left but found %d, %v", left, err)
+	}
+	expectAvailability(0, 2)
+
+	// the taken copies go when they are returned
+	if taken, err := library.WithdrawBook("9781617293092"); err != nil || taken != 2 {
+		t.Errorf("Expected 2 copies still taken but found %d, %v", taken, err)
+	}
+	if _, err := library.RemoveCopy("9781617293092"); err == nil {
+		t.Errorf("Expected an error when all copies are withdrawn")
+	}
+	expectError(t, ask(request, response, &CoolLibraryRequest{"9781617293092", ReturnBook}), "")
+	expectAvailability(0, 1)
+	expectError(t, ask(request, response, &CoolLibraryRequest{"9781617293092", BorrowBook}),
+		"Няма наличност на книга 9781617293092")
+
+	expectError(t, ask(request, response, NewMemberRequest(ReturnBook, "9781617293092", "alice")), "")
+	if _, ok := library.books["9781617293092"]; ok {
+		t.Errorf("Expected the book to be gone after its last copy is returned")
+	}
+	if _, err := library.RemoveCopy("9781617293092"); err == nil || err.Error() != "Непозната книга 9781617293092" {
+		t.Errorf("Expected an error for an unknown book but found %v", err)
+	}
+}
+
+func TestAddBookRequest(t *testing.T) {
+	library := NewFancyLibrary(1, WithCopyLimit(1))
+	request, response := library.Hello()
+	defer close(request)
+
+	book := &Book{ISBN: "0-9545-4001-8", Title: "Who Said the Race is Over?"}
+	add := NewAddRequest(book)
+	book.Title = "Changed"
+	added := ask(request, response, add)
+	expectError(t, added, "")
+	if stringer, _ := added.GetBook(); stringer.(*Book).Title != "Who Said the Race is Over?" {
+		t.Errorf("Expected the book as it was when the request was made but found %s", stringer)
+	}
+	if available, registered := added.GetAvailability(); available != 1 || registered != 1 {
+		t.Errorf("Expected 1 of 1 copies available but found %d of %d", available, registered)
+	}
+
+	expectError(t, ask(request, response, add), "Има 1 копия на книга 0954540018")
+	expectError(t, ask(request, response, NewAddRequest(&Book{ISBN: "0954540019"})), "Невалиден ISBN 0954540019")
+	expectError(t, ask(request, response, &CoolLibraryRequest{"0954540018", AddBook}), "Невалидна заявка")
+}
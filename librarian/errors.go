@@ -0,0 +1,155 @@
+package librarian
+
+import (
+	"errors"
+	"strconv"
+)
+
+// The kinds of the errors of the library.
+// The errors in the responses wrap them, so they can be checked with errors.Is
+var (
+	ErrUnknownBook       = errors.New("unknown book")
+	ErrNoCopiesAvailable = errors.New("no copies available")
+	ErrAllCopiesReturned = errors.New("all copies returned")
+	ErrCopyLimit         = errors.New("copy limit reached")
+	ErrInvalidRequest    = errors.New("invalid request")
+	ErrEmptyResponse     = errors.New("empty response")
+	ErrUnknownMember     = errors.New("unknown member")
+	ErrMemberExists      = errors.New("member exists")
+	ErrNotBorrowed       = errors.New("book not borrowed by the member")
+	ErrLentToMembers     = errors.New("copies lent to members")
+	ErrNothingToWithdraw = errors.New("no copies to withdraw")
+	ErrSessionExpired    = errors.New("session expired")
+	ErrAlreadyReserved   = errors.New("book already reserved by the member")
+	ErrNotReserved       = errors.New("book not reserved by the member")
+	ErrInvalidISBN       = errors.New("invalid isbn")
+	ErrFinesUnpaid       = errors.New("member has unpaid fines")
+	ErrRenewalLimit      = errors.New("renewal limit reached")
+	ErrReservedByOthers  = errors.New("book reserved by other members")
+)
+
+// LibraryError - an error of the library about a book or a member
+type LibraryError struct {
+	// one of the Err... kinds
+	Kind     error
+	ISBN     string
+	MemberID string
+	// the copy limit of the book for ErrCopyLimit, the renewal limit for ErrRenewalLimit
+	Limit int
+	// the unpaid fines of the member, for ErrFinesUnpaid
+	Amount   int
+	messages Messages
+}
+
+// Returns the message for the error from the catalogue of the library
+func (err *LibraryError) Error() string {
+	if message, ok := err.messages[err.Kind]; ok {
+		return message(err)
+	}
+	if message, ok := BulgarianMessages[err.Kind]; ok {
+		return message(err)
+	}
+	return err.Kind.Error()
+}
+
+// Returns the kind of the error
+func (err *LibraryError) Unwrap() error {
+	return err.Kind
+}
+
+// Messages - a catalogue with the messages for each kind of error
+type Messages map[error]func(err *LibraryError) string
+
+// The messages of the library unless it is created with others
+var BulgarianMessages = Messages{
+	ErrUnknownBook:       func(err *LibraryError) string { return "Непозната книга " + err.ISBN },
+	ErrNoCopiesAvailable: func(err *LibraryError) string { return "Няма наличност на книга " + err.ISBN },
+	ErrAllCopiesReturned: func(err *LibraryError) string { return "Всички копия са налични " + err.ISBN },
+	ErrCopyLimit: func(err *LibraryError) string {
+		return "Има " + strconv.Itoa(err.Limit) + " копия на книга " + err.ISBN
+	},
+	ErrInvalidRequest: func(err *LibraryError) string { return "Невалидна заявка" },
+	ErrEmptyResponse:  func(err *LibraryError) string { return "Празен отговор" },
+	ErrUnknownMember:  func(err *LibraryError) string { return "Непознат читател " + err.MemberID },
+	ErrMemberExists:   func(err *LibraryError) string { return "Вече има читател " + err.MemberID },
+	ErrNotBorrowed: func(err *LibraryError) string {
+		return "Читател " + err.MemberID + " няма книга " + err.ISBN
+	},
+	ErrLentToMembers: func(err *LibraryError) string {
+		return "Копията на книга " + err.ISBN + " са при читатели"
+	},
+	ErrNothingToWithdraw: func(err *LibraryError) string {
+		return "Няма копия за премахване на книга " + err.ISBN
+	},
+	ErrSessionExpired: func(err *LibraryError) string { return "Сесията изтече" },
+	ErrAlreadyReserved: func(err *LibraryError) string {
+		return "Читател " + err.MemberID + " вече чака книга " + err.ISBN
+	},
+	ErrNotReserved: func(err *LibraryError) string {
+		return "Читател " + err.MemberID + " не чака книга " + err.ISBN
+	},
+	ErrInvalidISBN: func(err *LibraryError) string { return "Невалиден ISBN " + err.ISBN },
+	ErrFinesUnpaid: func(err *LibraryError) string {
+		return "Читател " + err.MemberID + " има неплатени глоби " + strconv.Itoa(err.Amount)
+	},
+	ErrRenewalLimit: func(err *LibraryError) string {
+		return "Книга " + err.ISBN + " е подновена " + strconv.Itoa(err.Limit) + " пъти"
+	},
+	ErrReservedByOthers: func(err *LibraryError) string {
+		return "Книга " + err.ISBN + " е запазена от други читатели"
+	},
+}
+
+// The messages in English
+var EnglishMessages = Messages{
+	ErrUnknownBook:       func(err *LibraryError) string { return "Unknown book " + err.ISBN },
+	ErrNoCopiesAvailable: func(err *LibraryError) string { return "No available copies of book " + err.ISBN },
+	ErrAllCopiesReturned: func(err *LibraryError) string { return "All copies are available " + err.ISBN },
+	ErrCopyLimit: func(err *LibraryError) string {
+		return "There are " + strconv.Itoa(err.Limit) + " copies of book " + err.ISBN
+	},
+	ErrInvalidRequest: func(err *LibraryError) string { return "Invalid request" },
+	ErrEmptyResponse:  func(err *LibraryError) string { return "Empty response" },
+	ErrUnknownMember:  func(err *LibraryError) string { return "Unknown member " + err.MemberID },
+	ErrMemberExists:   func(err *LibraryError) string { return "There is already a member " + err.MemberID },
+	ErrNotBorrowed: func(err *LibraryError) string {
+		return "Member " + err.MemberID + " does not have book " + err.ISBN
+	},
+	ErrLentToMembers: func(err *LibraryError) string {
+		return "The copies of book " + err.ISBN + " are with members"
+	},
+	ErrNothingToWithdraw: func(err *LibraryError) string {
+		return "No copies to remove of book " + err.ISBN
+	},
+	ErrSessionExpired: func(err *LibraryError) string { return "The session has expired" },
+	ErrAlreadyReserved: func(err *LibraryError) string {
+		return "Member " + err.MemberID + " already waits for book " + err.ISBN
+	},
+	ErrNotReserved: func(err *LibraryError) string {
+		return "Member " + err.MemberID + " does not wait for book " + err.ISBN
+	},
+	ErrInvalidISBN: func(err *LibraryError) string { return "Invalid ISBN " + err.ISBN },
+	ErrFinesUnpaid: func(err *LibraryError) string {
+		return "Member " + err.MemberID + " has unpaid fines of " + strconv.Itoa(err.Amount)
+	},
+	ErrRenewalLimit: func(err *LibraryError) string {
+		return "Book " + err.ISBN + " has been renewed " + strconv.Itoa(err.Limit) + " times"
+	},
+	ErrReservedByOthers: func(err *LibraryError) string {
+		return "Book " + err.ISBN + " is reserved by other members"
+	},
+}
+
+// Sets the catalogue with the messages of the errors.
+// The kinds missing from it get the Bulgarian messages
+func WithMessages(messages Messages) Option {
+	return func(library *FancyLibrary) {
+		library.messages = messages
+	}
+}
+
+// Returns the error with the messages of the library
+func (library *FancyLibrary) fail(err LibraryError) error {
+	err.messages = library.messages
+	return &err
+}
@@ -0,0 +1,77 @@
+package librarian
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorKinds(t *testing.T) {
+	library := NewFancyLibrary(1, WithCopyLimit(1))
+	library.AddBookJSON([]byte(catalogueBooks[0]))
+	_, limitErr := library.AddBookJSON([]byte(catalogueBooks[0]))
+
+	request, response := library.Hello()
+	defer close(request)
+	errorOf := func(message LibraryRequest) error {
+		_, err := ask(request, response, message).GetBook()
+		return err
+	}
+
+	testTable := []struct {
+		err      error
+		kind     error
+		expected string
+	}{
+		{err: limitErr, kind: ErrCopyLimit, expected: "Има 1 копия на книга 9781617293092"},
+		{err: errorOf(&CoolLibraryRequest{"0954540018", BorrowBook}), kind: ErrUnknownBook,
+			expected: "Непозната книга 0954540018"},
+		{err: errorOf(&CoolLibraryRequest{"9781617293092", 42}), kind: ErrInvalidRequest,
+			expected: "Невалидна заявка"},
+		{err: errorOf(&CoolLibraryRequest{"9781617293092", BorrowBook}), kind: nil, expected: ""},
+		{err: errorOf(&CoolLibraryRequest{"9781617293092", BorrowBook}), kind: ErrNoCopiesAvailable,
+			expected: "Няма наличност на книга 9781617293092"},
+		{err: errorOf(&CoolLibraryRequest{"9781617293092", ReturnBook}), kind: nil, expected: ""},
+		{err: errorOf(&CoolLibraryRequest{"9781617293092", ReturnBook}), kind: ErrAllCopiesReturned,
+			expected: "Всички копия са налични 9781617293092"},
+	}
+	for _, testCase := range testTable {
+		if testCase.kind == nil {
+			if testCase.err != nil {
+				t.Errorf("There must not be an error but found %s", testCase.err.Error())
+			}
+			continue
+		}
+		if !errors.Is(testCase.err, testCase.kind) {
+			t.Errorf("Expected %v to be %v", testCase.err, testCase.kind)
+		}
+		var libraryErr *LibraryError
+		if !errors.As(testCase.err, &libraryErr) || libraryErr.Kind != testCase.kind {
+			t.Errorf("Expected a LibraryError of kind %v but found %#v", testCase.kind, testCase.err)
+		}
+		if testCase.err.Error() != testCase.expected {
+			t.Errorf("Expected error %q but found %q", testCase.expected, testCase.err.Error())
+		}
+	}
+}
+
+func TestEnglishMessages(t *testing.T) {
+	library := NewFancyLibrary(1, WithMessages(EnglishMessages))
+	library.AddMember("alice", "Alice")
+
+	request, response := library.Hello()
+	defer close(request)
+
+	expectError(t, ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "alice")),
+		"Unknown book 0954540018")
+	expectError(t, ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "bob")),
+		"Unknown member bob")
+	if err := library.AddMember("alice", "Alice"); err == nil || err.Error() != "There is already a member alice" {
+		t.Errorf("Expected an error for the second alice but found %v", err)
+	}
+
+	// the kinds missing from the catalogue are in Bulgarian
+	library = NewFancyLibrary(1, WithMessages(Messages{}))
+	if _, err := library.RemoveCopy("0954540018"); err == nil || err.Error() != "Непозната книга 0954540018" {
+		t.Errorf("Expected the Bulgarian message but found %v", err)
+	}
+}
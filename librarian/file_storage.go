@@ -0,0 +1,178 @@
+package librarian
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// The files of a FileStorage in its directory
+const (
+	// all the records when the journal was last compacted
+	SnapshotFile = "library.json"
+	// the changes after the snapshot, one JSON State per line
+	JournalFile = "library.journal"
+)
+
+// FileStorage - a Storage in a directory with a snapshot and an append only journal.
+// A change cut in the middle of writing is removed from the end of the journal when it is opened
+type FileStorage struct {
+	dir     string
+	mutex   sync.Mutex
+	journal *os.File
+	// the records of the snapshot and the journal
+	saved *MemoryStorage
+	// the first failed write, after it nothing is written
+	err error
+}
+
+// Opens the storage in dir, creating its files if needed.
+// The books and the members are read from the snapshot and the journal
+func OpenFileStorage(dir string) (*FileStorage, error) {
+	saved := NewMemoryStorage()
+	data, err := ioutil.ReadFile(filepath.Join(dir, SnapshotFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		state := State{}
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("bad snapshot in %s: %s", dir, err)
+		}
+		saved.Save(state)
+	}
+
+	path := filepath.Join(dir, JournalFile)
+	data, err = ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	// the last change is complete only if it ends with a new line
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	reader := bufio.NewReader(bytes.NewReader(data[:complete]))
+	for line := 1; ; line++ {
+		change, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		state := State{}
+		if err := json.Unmarshal(change, &state); err != nil {
+			return nil, fmt.Errorf("bad change %d in %s: %s", line, path, err)
+		}
+		saved.Save(state)
+	}
+
+	journal, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := journal.Truncate(int64(complete)); err != nil {
+		journal.Close()
+		return nil, err
+	}
+	if _, err := journal.Seek(int64(complete), io.SeekStart); err != nil {
+		journal.Close()
+		return nil, err
+	}
+	return &FileStorage{dir: dir, journal: journal, saved: saved}, nil
+}
+
+// Returns the books and the members from the snapshot and the journal
+func (storage *FileStorage) Load() (State, error) {
+	return storage.saved.Load()
+}
+
+// Writes the changed records at the end of the journal.
+// They are on the disk when Save returns
+func (storage *FileStorage) Save(changed State) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if storage.err != nil {
+		return storage.err
+	}
+
+	line, err := json.Marshal(changed)
+	if err != nil {
+		return err
+	}
+	if _, err := storage.journal.Write(append(line, '\n')); err != nil {
+		storage.err = fmt.Errorf("journal is not writable: %s", err)
+		return storage.err
+	}
+	if err := storage.journal.Sync(); err != nil {
+		storage.err = fmt.Errorf("journal is not writable: %s", err)
+		return storage.err
+	}
+	return storage.saved.Save(changed)
+}
+
+// Writes all the records to the snapshot and empties the journal.
+// The snapshot is replaced at once, so it is never left half written.
+// If the journal is not emptied its changes are written again on the snapshot,
+// which changes nothing
+func (storage *FileStorage) Compact() error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if storage.err != nil {
+		return storage.err
+	}
+	return storage.compact()
+}
+
+// Has to be called while holding the mutex
+func (storage *FileStorage) compact() error {
+	state, _ := storage.saved.Load()
+	snapshot, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	temp, err := ioutil.TempFile(storage.dir, SnapshotFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(snapshot); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(temp.Name(), filepath.Join(storage.dir, SnapshotFile)); err != nil {
+		return err
+	}
+
+	if err := storage.journal.Truncate(0); err != nil {
+		return err
+	}
+	_, err = storage.journal.Seek(0, io.SeekStart)
+	return err
+}
+
+// Compacts the journal and closes it
+func (storage *FileStorage) Close() error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if storage.err != nil {
+		storage.journal.Close()
+		return storage.err
+	}
+	storage.err = errors.New("storage is closed")
+	if err := storage.compact(); err != nil {
+		storage.journal.Close()
+		return err
+	}
+	return storage.journal.Close()
+}
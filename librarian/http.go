@@ -0,0 +1,244 @@
+package librarian
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// LibraryHandler - answers HTTP requests with the librarians of a library.
+// Each request is served by a librarian of its own, so no more requests
+// than the librarians are served at once
+type LibraryHandler struct {
+	mux     *http.ServeMux
+	library *FancyLibrary
+}
+
+// The library handler implements Handler interface
+func (h *LibraryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Creates a handler for the library
+func NewLibraryHandler(library *FancyLibrary) *LibraryHandler {
+	handler := &LibraryHandler{library: library}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/books/", handler.addBook)
+	mux.HandleFunc("/books/borrow/", handler.borrow)
+	mux.HandleFunc("/books/return/", handler.giveBack)
+	mux.HandleFunc("/books/availability/", handler.availability)
+	mux.HandleFunc("/books/search/", handler.search)
+	handler.mux = mux
+	return handler
+}
+
+// BookStatus - a book with its copies as the handler answers with it
+type BookStatus struct {
+	Book       *Book `json:"book"`
+	Available  int   `json:"available"`
+	Registered int   `json:"registered"`
+	// when a member has to return the book, only when a member borrows it
+	Due *time.Time `json:"due,omitempty"`
+}
+
+// Type used for creating json for /books/search/ responses
+type BookList struct {
+	Books []BookStatus `json:"books"`
+}
+
+// Returns the HTTP status for the error of a library response
+func statusOf(err error) int {
+	libraryErr := &LibraryError{}
+	if !errors.As(err, &libraryErr) {
+		return http.StatusInternalServerError
+	}
+	switch libraryErr.Kind {
+	case ErrUnknownBook, ErrUnknownMember:
+		return http.StatusNotFound
+	case ErrInvalidRequest, ErrInvalidISBN:
+		return http.StatusBadRequest
+	case ErrSessionExpired:
+		return http.StatusServiceUnavailable
+	case ErrFinesUnpaid:
+		return http.StatusForbidden
+	}
+	return http.StatusConflict
+}
+
+// Gets a free librarian for the request.
+// Answers with 503 and returns false if the client goes away before a librarian is free
+func (h *LibraryHandler) hello(w http.ResponseWriter, r *http.Request) (chan<- LibraryRequest, <-chan LibraryResponse, bool) {
+	request, response, err := h.library.HelloContext(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return nil, nil, false
+	}
+	return request, response, true
+}
+
+// Sends the request to a librarian and returns the response.
+// Returns nil if there is no free librarian for the request
+func (h *LibraryHandler) ask(w http.ResponseWriter, r *http.Request, message LibraryRequest) LibraryResponse {
+	request, response, ok := h.hello(w, r)
+	if !ok {
+		return nil
+	}
+	defer close(request)
+	request <- message
+	return <-response
+}
+
+// Writes the value as json
+func writeJSON(w http.ResponseWriter, value interface{}, code int) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// Returns the status of the book, which has to be a copy of the book in the library
+func bookStatus(book *Book) BookStatus {
+	return BookStatus{Book: book, Available: book.availableCount, Registered: book.registeredCount}
+}
+
+// Writes the book of a librarian's response, or its error.
+// The book of the response has to be a copy, as for the member requests
+func writeBook(w http.ResponseWriter, response LibraryResponse) {
+	stringer, err := response.GetBook()
+	if err != nil {
+		http.Error(w, err.Error(), statusOf(err))
+		return
+	}
+	status := BookStatus{Book: stringer.(*Book)}
+	status.Available, status.Registered = response.GetAvailability()
+	if loan, ok := response.(LibraryLoanResponse); ok {
+		due := loan.GetDueDate()
+		status.Due = &due
+	}
+	writeJSON(w, status, http.StatusOK)
+}
+
+// Responsible to answer to POST /books/ requests.
+// The book is read as JSON or XML depending on the Content-Type
+func (h *LibraryHandler) addBook(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/books/" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	book := new(Book)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		err = json.Unmarshal(data, book)
+	case "application/xml", "text/xml":
+		err = xml.Unmarshal(data, book)
+	default:
+		http.Error(w, "Only application/json and application/xml are supported", http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := h.ask(w, r, NewAddRequest(book))
+	if response == nil {
+		return
+	}
+	added, err := response.GetBook()
+	if err != nil {
+		http.Error(w, err.Error(), statusOf(err))
+		return
+	}
+	writeJSON(w, bookStatus(added.(*Book)), http.StatusCreated)
+}
+
+// Responsible to answer to POST /books/borrow/?isbn=&member= requests.
+// Without a member the loan is not recorded
+func (h *LibraryHandler) borrow(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, BorrowBook)
+}
+
+// Responsible to answer to POST /books/return/?isbn=&member= requests
+func (h *LibraryHandler) giveBack(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, ReturnBook)
+}
+
+func (h *LibraryHandler) change(w http.ResponseWriter, r *http.Request, requestType int) {
+	if r.Method != "POST" {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	response := h.ask(w, r, NewMemberRequest(requestType, query.Get("isbn"), query.Get("member")))
+	if response != nil {
+		writeBook(w, response)
+	}
+}
+
+// Responsible to answer to GET /books/availability/?isbn= requests
+func (h *LibraryHandler) availability(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	response := h.ask(w, r, &CoolLibraryRequest{r.URL.Query().Get("isbn"), GetAvailability})
+	if response != nil {
+		writeBook(w, response)
+	}
+}
+
+// Responsible to answer to GET /books/search/ requests.
+// Searches with one of ?title=, ?author=, ?genre= or ?unavailable=true,
+// lists all the books without them
+func (h *LibraryHandler) search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	request := NewCatalogueRequest(ListBooks, "")
+	switch {
+	case query.Get("title") != "":
+		request = NewCatalogueRequest(SearchByTitle, query.Get("title"))
+	case query.Get("author") != "":
+		request = NewCatalogueRequest(SearchByAuthor, query.Get("author"))
+	case query.Get("genre") != "":
+		request = NewCatalogueRequest(SearchByGenre, query.Get("genre"))
+	case query.Get("unavailable") == "true":
+		request = NewCatalogueRequest(ListUnavailable, "")
+	}
+
+	response := h.ask(w, r, request)
+	if response == nil {
+		return
+	}
+	books, err := response.(LibraryBooksResponse).GetBooks()
+	if err != nil {
+		http.Error(w, err.Error(), statusOf(err))
+		return
+	}
+	list := BookList{Books: make([]BookStatus, len(books))}
+	for i, book := range books {
+		list.Books[i] = bookStatus(book.(*Book))
+	}
+	writeJSON(w, list, http.StatusOK)
+}
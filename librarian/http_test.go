@@ -0,0 +1,152 @@
+package librarian
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Sends the request to the server and returns the status code and the body
+func send(t *testing.T, method string, url string, contentType string, body string) (int, string) {
+	t.Helper()
+	request, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %s", err)
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Error sending request: %s", err)
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("Error reading from response: %s", err)
+	}
+	return response.StatusCode, string(data)
+}
+
+func TestHTTPHandler(t *testing.T) {
+	library := NewFancyLibrary(2)
+	library.AddMember("alice", "Alice")
+	server := httptest.NewServer(NewLibraryHandler(library))
+	defer server.Close()
+
+	xmlBook := `<book isbn="0954540018"><title>Who Said the Race is Over?</title>
+		<author><first_name>Anno</first_name><last_name>Birkin</last_name></author></book>`
+
+	testTable := []struct {
+		method, path, contentType, body string
+		code                            int
+		expected                        string
+	}{
+		{"POST", "/books/", "application/json", catalogueBooks[0], http.StatusCreated, `"available":1`},
+		{"POST", "/books/", "application/json; charset=utf-8", catalogueBooks[0], http.StatusCreated, `"available":2`},
+		{"POST", "/books/", "application/xml", xmlBook, http.StatusCreated, `"isbn":"0954540018"`},
+		{"POST", "/books/", "text/plain", catalogueBooks[0], http.StatusUnsupportedMediaType, ""},
+		{"POST", "/books/", "application/json", `{"isbn":`, http.StatusBadRequest, ""},
+		{"POST", "/books/", "application/json", `{"isbn": "123"}`, http.StatusBadRequest, "Невалиден ISBN 123"},
+		{"GET", "/books/", "", "", http.StatusMethodNotAllowed, "Only POST method is allowed"},
+		{"POST", "/books/borrow/?isbn=0954540018&member=alice", "", "", http.StatusOK, `"due":`},
+		{"POST", "/books/borrow/?isbn=0954540018", "", "", http.StatusConflict, "Няма наличност на книга 0954540018"},
+		{"POST", "/books/borrow/?isbn=0954540018&member=bob", "", "", http.StatusNotFound, "Непознат читател bob"},
+		{"POST", "/books/borrow/?isbn=1234567897", "", "", http.StatusNotFound, "Непозната книга 1234567897"},
+		{"GET", "/books/borrow/?isbn=0954540018", "", "", http.StatusMethodNotAllowed, ""},
+		{"GET", "/books/availability/?isbn=9780954540012", "", "", http.StatusOK, `"available":0,"registered":1`},
+		{"GET", "/books/availability/?isbn=x", "", "", http.StatusNotFound, ""},
+		{"POST", "/books/return/?isbn=0954540018", "", "", http.StatusConflict, "Копията на книга 0954540018 са при читатели"},
+		{"POST", "/books/return/?isbn=0954540018&member=alice", "", "", http.StatusOK, `"available":1`},
+		{"POST", "/books/return/?isbn=9781617293092", "", "", http.StatusConflict, ""},
+		{"GET", "/books/search/?title=GO", "", "", http.StatusOK, `"title":"Learn Go"`},
+		{"GET", "/books/search/?author=birkin", "", "", http.StatusOK, `"title":"Who Said the Race is Over?"`},
+		{"GET", "/books/search/?genre=poetry", "", "", http.StatusOK, `{"books":[]}`},
+		{"GET", "/books/other/", "", "", http.StatusNotFound, ""},
+	}
+
+	for _, testCase := range testTable {
+		code, body := send(t, testCase.method, server.URL+testCase.path, testCase.contentType, testCase.body)
+		if code != testCase.code || !strings.Contains(body, testCase.expected) {
+			t.Errorf("Expected %d with %s for %s %s but found %d with %s",
+				testCase.code, testCase.expected, testCase.method, testCase.path, code, body)
+		}
+	}
+
+	_, body := send(t, "GET", server.URL+"/books/search/", "", "")
+	list := BookList{}
+	if err := json.Unmarshal([]byte(body), &list); err != nil {
+		t.Fatalf("Error decoding json: %s", err)
+	}
+	if len(list.Books) != 2 || list.Books[0].Book.ISBN != "9781617293092" || list.Books[0].Available != 2 {
+		t.Errorf("Expected the two books but found %s", body)
+	}
+}
+
+func TestHTTPWaitsForLibrarian(t *testing.T) {
+	library := NewFancyLibrary(1)
+	library.AddBookJSON([]byte(catalogueBooks[0]))
+	handler := NewLibraryHandler(library)
+
+	// the only librarian is busy
+	request, _ := library.Hello()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/books/availability/?isbn=9781617293092", nil).WithContext(ctx))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected %d while the librarian is busy but found %d", http.StatusServiceUnavailable, recorder.Code)
+	}
+
+	close(request)
+	waitForStats(t, library, PoolStats{Busy: 0, Free: 1})
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/books/availability/?isbn=9781617293092", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected %d after the librarian is free but found %d", http.StatusOK, recorder.Code)
+	}
+	waitForStats(t, library, PoolStats{Busy: 0, Free: 1})
+}
+
+func TestHTTPConcurrentRequests(t *testing.T) {
+	library := NewFancyLibrary(3)
+	library.AddBookJSON([]byte(catalogueBooks[0]))
+	library.AddBookJSON([]byte(catalogueBooks[1]))
+	for _, member := range []string{"alice", "bob"} {
+		library.AddMember(member, member)
+	}
+	server := httptest.NewServer(NewLibraryHandler(library))
+	defer server.Close()
+
+	var wait sync.WaitGroup
+	paths := []string{"/books/borrow/?isbn=0954540018&member=alice", "/books/return/?isbn=0954540018&member=alice",
+		"/books/borrow/?isbn=0954540018", "/books/return/?isbn=0954540018"}
+	for i := 0; i < 4; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			for j := 0; j < 20; j++ {
+				// most of the requests fail, so their changes are restored meanwhile
+				code, body := send(t, "POST", server.URL+paths[(i+j)%len(paths)], "", "")
+				if code != http.StatusOK && code != http.StatusConflict {
+					t.Errorf("Expected %d or %d but found %d with %s", http.StatusOK, http.StatusConflict, code, body)
+				}
+				send(t, "GET", server.URL+"/books/availability/?isbn=0954540018", "", "")
+				send(t, "POST", server.URL+"/books/", "application/json", catalogueBooks[0])
+			}
+		}(i)
+	}
+	wait.Wait()
+
+	_, body := send(t, "GET", server.URL+"/books/search/?title=learn", "", "")
+	if !strings.Contains(body, `"registered":4`) {
+		t.Errorf("Expected 4 copies of Learn Go but found %s", body)
+	}
+}
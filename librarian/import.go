@@ -0,0 +1,395 @@
+package librarian
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Formats of the bulk import and export.
+// Each record is a book with the fields "copies" and "available" next to the others.
+const (
+	// a JSON array of books
+	FormatJSON = "json"
+	// one JSON book on each line
+	FormatJSONLines = "jsonl"
+	// <books> with a <book> like the ones of AddBookXML for each book
+	FormatXML = "xml"
+	// a header with the names of the columns and a book on each row - see csvColumns
+	FormatCSV = "csv"
+	// records of MARC fields, one on each line - see marc.go
+	FormatMARC = "marc"
+)
+
+// The columns of the exported CSV, the imported CSV can have them in any order.
+// The ratings are separated with ";". The other columns go to the extras of the book
+var csvColumns = []string{"isbn", "title", "first_name", "last_name", "genre", "pages", "ratings", "copies", "available"}
+
+// ImportResult - what happened with a record of the import
+type ImportResult struct {
+	// the place of the record, starting from 1
+	Index int
+	ISBN  string
+	// the copies added by the record
+	Added int
+	// the copies in the library after the record
+	Copies int
+	Err    error
+}
+
+// ImportReport - the results of all the records of an import
+type ImportReport struct {
+	Results []ImportResult
+	// the copies added by all the records
+	Added  int
+	Failed int
+}
+
+// Adds the books read from reader in the format.
+// A record adds its "copies", one if there is no such field, and fails if they are less than one
+// or are given more than once.
+// It stops at the first error, so the copies over the limit are not added and the report has the error.
+// Returns an error if the reader can not be read as the format -
+// the records before it are still added
+func (library *FancyLibrary) Import(format string, reader io.Reader) (ImportReport, error) {
+	report := ImportReport{}
+	add := func(book *Book, err error) {
+		result := ImportResult{Index: len(report.Results) + 1, Err: err}
+		if err == nil {
+			result.Added, result.Copies, result.Err = library.addCopies(book)
+			// the isbn of the book in the library, if it is valid
+			result.ISBN = book.ISBN
+		}
+		if result.Err != nil {
+			report.Failed++
+		}
+		report.Added += result.Added
+		report.Results = append(report.Results, result)
+	}
+
+	switch format {
+	case FormatJSON:
+		return report, importJSON(reader, add)
+	case FormatJSONLines:
+		return report, importJSONLines(reader, add)
+	case FormatXML:
+		return report, importXML(reader, add)
+	case FormatCSV:
+		return report, importCSV(reader, add)
+	case FormatMARC:
+		return report, importMARC(reader, add)
+	}
+	return report, library.fail(LibraryError{Kind: ErrInvalidRequest})
+}
+
+// Adds the copies of the book the record asks for.
+// A record with more than one field for the copies fails, as it is not clear which one counts
+func (library *FancyLibrary) addCopies(book *Book) (added int, copies int, err error) {
+	// the names of the fields are matched in any case, as the columns of the CSV
+	var counts []interface{}
+	for name, value := range book.Extras {
+		switch strings.ToLower(name) {
+		case "copies":
+			counts = append(counts, value)
+			delete(book.Extras, name)
+		case "available":
+			// the availability can not be imported, the new copies are in the library
+			delete(book.Extras, name)
+		}
+	}
+	count := 1
+	switch len(counts) {
+	case 0:
+	case 1:
+		if count, err = strconv.Atoi(strings.TrimSpace(fmt.Sprint(counts[0]))); err != nil {
+			return 0, 0, err
+		}
+	default:
+		return 0, 0, library.fail(LibraryError{Kind: ErrInvalidRequest, ISBN: book.ISBN})
+	}
+	if count < 1 {
+		return 0, 0, library.fail(LibraryError{Kind: ErrInvalidRequest, ISBN: book.ISBN})
+	}
+	if len(book.Extras) == 0 {
+		book.Extras = nil
+	}
+	for added < count {
+		if copies, err = library.addBook(book); err != nil {
+			return added, copies, err
+		}
+		added++
+	}
+	return added, copies, nil
+}
+
+// Reads a JSON array of books
+func importJSON(reader io.Reader, add func(*Book, error)) error {
+	decoder := json.NewDecoder(reader)
+	if token, err := decoder.Token(); err != nil {
+		return err
+	} else if token != json.Delim('[') {
+		return fmt.Errorf("expected a JSON array but found %v", token)
+	}
+	for decoder.More() {
+		var record json.RawMessage
+		if err := decoder.Decode(&record); err != nil {
+			return err
+		}
+		add(readJSONBook(record))
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+// Reads a JSON book on each line, the empty lines are skipped
+func importJSONLines(reader io.Reader, add func(*Book, error)) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) != 0 {
+			add(readJSONBook(line))
+		}
+	}
+	return scanner.Err()
+}
+
+func readJSONBook(data []byte) (*Book, error) {
+	book := new(Book)
+	if err := json.Unmarshal(data, book); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// Reads the <book> elements of the document, wherever they are
+func importXML(reader io.Reader, add func(*Book, error)) error {
+	decoder := xml.NewDecoder(reader)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "book" {
+			book := new(Book)
+			if err := decoder.DecodeElement(book, &start); err != nil {
+				return err
+			}
+			add(book, nil)
+		}
+	}
+}
+
+// Reads the header and then a book on each row
+func importCSV(reader io.Reader, add func(*Book, error)) error {
+	records := csv.NewReader(reader)
+	// a row with missing columns is still read, its book has them empty
+	records.FieldsPerRecord = -1
+	header, err := records.Read()
+	if err != nil {
+		return err
+	}
+	for {
+		row, err := records.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		add(readCSVBook(header, row))
+	}
+}
+
+func readCSVBook(header []string, row []string) (*Book, error) {
+	book := new(Book)
+	for i, name := range header {
+		value := ""
+		if i < len(row) {
+			value = strings.TrimSpace(row[i])
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "isbn":
+			book.ISBN = value
+		case "title":
+			book.Title = value
+		case "first_name":
+			book.Author.FirstName = value
+		case "last_name":
+			book.Author.LastName = value
+		case "genre":
+			book.Genre = value
+		case "pages":
+			if value == "" {
+				continue
+			}
+			pages, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, err
+			}
+			book.Pages = pages
+		case "ratings":
+			for _, rating := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' }) {
+				number, err := strconv.Atoi(strings.TrimSpace(rating))
+				if err != nil {
+					return nil, err
+				}
+				book.Ratings = append(book.Ratings, number)
+			}
+		default:
+			if value == "" {
+				continue
+			}
+			if book.Extras == nil {
+				book.Extras = make(map[string]interface{})
+			}
+			book.Extras[name] = value
+		}
+	}
+	return book, nil
+}
+
+// A book of the catalogue with its copies
+type exportedBook struct {
+	book       Book
+	registered int
+	available  int
+}
+
+// Writes all the books of the library with their copies in the format, ordered by isbn.
+// The extras of the books are not written in CSV and only the MARC fields of them are written in MARC
+func (library *FancyLibrary) Export(format string, writer io.Writer) error {
+	library.mutex.Lock()
+	if err := library.refreshAllHolds(); err != nil {
+		library.mutex.Unlock()
+		return err
+	}
+	books := make([]exportedBook, 0, len(library.books))
+	for _, book := range library.books {
+		books = append(books, exportedBook{book: *book,
+			registered: book.registeredCount, available: book.availableCount})
+	}
+	library.mutex.Unlock()
+	sort.Slice(books, func(i, j int) bool {
+		return books[i].book.ISBN < books[j].book.ISBN
+	})
+
+	switch format {
+	case FormatJSON, FormatJSONLines:
+		return exportJSON(writer, books, format == FormatJSON)
+	case FormatXML:
+		return exportXML(writer, books)
+	case FormatCSV:
+		return exportCSV(writer, books)
+	case FormatMARC:
+		return exportMARC(writer, books)
+	}
+	return library.fail(LibraryError{Kind: ErrInvalidRequest})
+}
+
+// Returns the book with its copies in the extras
+func (exported exportedBook) withCopies() Book {
+	book := exported.book
+	book.Extras = map[string]interface{}{"copies": exported.registered, "available": exported.available}
+	for name, value := range exported.book.Extras {
+		if _, ok := book.Extras[name]; !ok {
+			book.Extras[name] = value
+		}
+	}
+	return book
+}
+
+func exportJSON(writer io.Writer, books []exportedBook, array bool) error {
+	buffered := bufio.NewWriter(writer)
+	separator, end := "", "\n"
+	if array {
+		buffered.WriteString("[\n")
+		end = "\n]\n"
+	}
+	for _, exported := range books {
+		data, err := json.Marshal(exported.withCopies())
+		if err != nil {
+			return err
+		}
+		buffered.WriteString(separator)
+		buffered.Write(data)
+		separator = "\n"
+		if array {
+			separator = ",\n"
+		}
+	}
+	if len(books) > 0 || array {
+		buffered.WriteString(end)
+	}
+	return buffered.Flush()
+}
+
+// The <book> of the export
+type xmlBook struct {
+	XMLName xml.Name `xml:"book"`
+	*Book
+	Copies    int          `xml:"copies"`
+	Available int          `xml:"available"`
+	Extras    []xmlElement `xml:",any"`
+}
+
+type xmlElement struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+func exportXML(writer io.Writer, books []exportedBook) error {
+	buffered := bufio.NewWriter(writer)
+	buffered.WriteString(xml.Header + "<books>\n")
+	encoder := xml.NewEncoder(buffered)
+	encoder.Indent("  ", "  ")
+	for _, exported := range books {
+		element := xmlBook{Book: &exported.book, Copies: exported.registered, Available: exported.available}
+		names := make([]string, 0, len(exported.book.Extras))
+		for name := range exported.book.Extras {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			element.Extras = append(element.Extras,
+				xmlElement{XMLName: xml.Name{Local: name}, Value: fmt.Sprint(exported.book.Extras[name])})
+		}
+		if err := encoder.Encode(element); err != nil {
+			return err
+		}
+		buffered.WriteString("\n")
+	}
+	buffered.WriteString("</books>\n")
+	return buffered.Flush()
+}
+
+func exportCSV(writer io.Writer, books []exportedBook) error {
+	records := csv.NewWriter(writer)
+	records.Write(csvColumns)
+	for _, exported := range books {
+		book := exported.book
+		ratings := make([]string, len(book.Ratings))
+		for i, rating := range book.Ratings {
+			ratings[i] = strconv.Itoa(rating)
+		}
+		pages := ""
+		if book.Pages != 0 {
+			pages = strconv.Itoa(book.Pages)
+		}
+		records.Write([]string{book.ISBN, book.Title, book.Author.FirstName, book.Author.LastName,
+			book.Genre, pages, strings.Join(ratings, ";"),
+			strconv.Itoa(exported.registered), strconv.Itoa(exported.available)})
+	}
+	records.Flush()
+	return records.Error()
+}
@@ -0,0 +1,213 @@
+package librarian
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestImportFormats(t *testing.T) {
+	inputs := map[string]string{
+		FormatJSON: `[
+			{"isbn": "0954540018", "title": "Who Said the Race is Over?", "copies": 2},
+			{"isbn": "9781617293092", "title": "Learn Go", "genre": "programming"},
+			{"isbn": "0-9545-4001-8", "title": "Who Said the Race is Over?", "copies": 3},
+			{"isbn": "0954540019", "title": "Typo"},
+			{"isbn": "9780134190440", "pages": "many"}
+		]`,
+		FormatJSONLines: `{"isbn": "0954540018", "title": "Who Said the Race is Over?", "copies": 2}
+			{"isbn": "9781617293092", "title": "Learn Go", "genre": "programming"}
+
+			{"isbn": "0-9545-4001-8", "title": "Who Said the Race is Over?", "copies": 3}
+			{"isbn": "0954540019", "title": "Typo"}
+			{"isbn": "9780134190440", "pages": "many"}`,
+		FormatXML: `<books>
+			<book isbn="0954540018"><title>Who Said the Race is Over?</title><copies>2</copies></book>
+			<book isbn="9781617293092"><title>Learn Go</title><genre>programming</genre></book>
+			<book isbn="0-9545-4001-8"><title>Who Said the Race is Over?</title><copies>3</copies></book>
+			<book isbn="0954540019"><title>Typo</title></book>
+			<book isbn="9780134190440"><copies>many</copies></book>
+		</books>`,
+		FormatCSV: "isbn,title,genre,copies\n" +
+			"0954540018,Who Said the Race is Over?,,2\n" +
+			"9781617293092,Learn Go,programming\n" +
+			"0-9545-4001-8,Who Said the Race is Over?,,3\n" +
+			"0954540019,Typo,,\n" +
+			"9780134190440,,,many\n",
+		FormatMARC: `=LDR  00000nam a2200000 a 4500
+=020  \\$a0954540018 (pbk.)
+=245  10$aWho Said the Race is Over? /
+=999  \\$c2
+
+=020  \\$a9781617293092
+=245  10$aLearn Go
+=655  \\$aprogramming.
+
+=020  \\$a0-9545-4001-8
+=245  10$aWho Said the Race is Over?
+=999  \\$c3
+
+=020  \\$a0954540019
+=245  10$aTypo
+
+=020  \\$a9780134190440
+=300  \\$amany p.
+`,
+	}
+	for format, input := range inputs {
+		library := NewFancyLibrary(1)
+		report, err := library.Import(format, strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("There must not be an error for %s but found %s", format, err.Error())
+		}
+		if len(report.Results) != 5 || report.Added != 5 || report.Failed != 3 {
+			t.Fatalf("Expected 5 records with 5 copies and 3 failures for %s but found %+v", format, report)
+		}
+		expected := []ImportResult{
+			{Index: 1, ISBN: "0954540018", Added: 2, Copies: 2},
+			{Index: 2, ISBN: "9781617293092", Added: 1, Copies: 1},
+			{Index: 3, ISBN: "0954540018", Added: 2, Copies: 4},
+		}
+		for i, result := range expected {
+			found := report.Results[i]
+			if found.Err != nil && i == 2 && errors.Is(found.Err, ErrCopyLimit) {
+				found.Err = nil
+			}
+			if found != result {
+				t.Errorf("Expected %+v for %s but found %+v", result, format, report.Results[i])
+			}
+		}
+		if !errors.Is(report.Results[3].Err, ErrInvalidISBN) {
+			t.Errorf("Expected an invalid isbn for %s but found %v", format, report.Results[3].Err)
+		}
+		if report.Results[4].Err == nil {
+			t.Errorf("Expected an error for the bad number of %s", format)
+		}
+		if book := library.books["9781617293092"]; book == nil || book.Genre != "programming" || book.Extras != nil {
+			t.Errorf("Expected the book with its genre for %s but found %+v", format, book)
+		}
+	}
+
+	library := NewFancyLibrary(1)
+	report, err := library.Import(FormatJSON, strings.NewReader(`[{"isbn": "0954540018"}, {"isbn": `))
+	if err == nil || report.Added != 1 {
+		t.Errorf("Expected the first book and an error but found %+v, %v", report, err)
+	}
+}
+
+func TestImportCopies(t *testing.T) {
+	library := NewFancyLibrary(1)
+	report, err := library.Import(FormatCSV, strings.NewReader("ISBN,Title,Copies,Available\n"+
+		"0954540018,Who Said the Race is Over?,3,1\n"+
+		"9781617293092,Learn Go,0,\n"+
+		"9780134190440,The Go Programming Language,-2,\n"))
+	if err != nil {
+		t.Fatalf("There must not be an error but found %s", err.Error())
+	}
+	if report.Added != 3 || report.Failed != 2 || report.Results[0].Copies != 3 {
+		t.Errorf("Expected 3 copies of the first book and 2 failures but found %+v", report)
+	}
+	for _, result := range report.Results[1:] {
+		if !errors.Is(result.Err, ErrInvalidRequest) || result.Added != 0 {
+			t.Errorf("Expected an invalid request for no copies but found %+v", result)
+		}
+	}
+	if book := library.books["0954540018"]; book == nil || book.Extras != nil {
+		t.Errorf("Expected the copies to be left out of the extras but found %+v", book)
+	}
+	// it is not clear which of the copies counts
+	for i := 0; i < 10; i++ {
+		report, _ = library.Import(FormatJSON,
+			strings.NewReader(`[{"isbn": "9781617293092", "copies": 1, "Copies": 2}]`))
+		if !errors.Is(report.Results[0].Err, ErrInvalidRequest) || report.Added != 0 {
+			t.Fatalf("Expected an invalid request for two copies fields but found %+v", report)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	library := NewFancyLibrary(1)
+	library.AddBookJSON([]byte(`{"isbn": "9781617293092", "title": "Learn Go",
+		"author": {"first_name": "Nathan", "last_name": "Youngman"}, "publisher": "Manning"}`))
+	library.AddBookJSON([]byte(`{"isbn": "0954540018", "title": "Who Said the Race is Over?",
+		"author": {"first_name": "Anno", "last_name": "Birkin"}, "genre": "poetry", "pages": 80, "ratings": [5, 4]}`))
+	library.AddBookJSON([]byte(catalogueBooks[1]))
+	request, response := library.Hello()
+	ask(request, response, &CoolLibraryRequest{"0954540018", BorrowBook})
+	close(request)
+
+	testTable := []struct {
+		format   string
+		expected string
+	}{
+		{format: FormatJSONLines, expected: `{"author":{"first_name":"Anno","last_name":"Birkin"},"available":1,"copies":2,` +
+			`"genre":"poetry","isbn":"0954540018","pages":80,"ratings":[5,4],"title":"Who Said the Race is Over?"}
+{"author":{"first_name":"Nathan","last_name":"Youngman"},"available":1,"copies":1,` +
+			`"isbn":"9781617293092","publisher":"Manning","title":"Learn Go"}
+`},
+		{format: FormatCSV, expected: `isbn,title,first_name,last_name,genre,pages,ratings,copies,available
+0954540018,Who Said the Race is Over?,Anno,Birkin,poetry,80,5;4,2,1
+9781617293092,Learn Go,Nathan,Youngman,,,,1,1
+`},
+		{format: FormatMARC, expected: `=020  \\$a0954540018
+=100  1\$aBirkin, Anno
+=245  10$aWho Said the Race is Over?
+=300  \\$a80 p.
+=655  \\$apoetry
+=999  \\$c2$d1$r5;4
+
+=020  \\$a9781617293092
+=100  1\$aYoungman, Nathan
+=245  10$aLearn Go
+=999  \\$c1$d1
+`},
+	}
+	for _, testCase := range testTable {
+		var output bytes.Buffer
+		if err := library.Export(testCase.format, &output); err != nil {
+			t.Fatalf("There must not be an error but found %s", err.Error())
+		}
+		if output.String() != testCase.expected {
+			t.Errorf("Expected\n---\n%s\n---\nbut found\n---\n%s\n---\n", testCase.expected, output.String())
+		}
+	}
+
+	// the export of each format can be imported back
+	for _, format := range []string{FormatJSON, FormatJSONLines, FormatXML, FormatCSV, FormatMARC} {
+		var exported, reexported bytes.Buffer
+		library.Export(format, &exported)
+		imported := NewFancyLibrary(1)
+		if report, err := imported.Import(format, bytes.NewReader(exported.Bytes())); err != nil || report.Failed != 0 {
+			t.Fatalf("Expected the export to be imported but found %+v, %v", report, err)
+		}
+		imported.Export(FormatCSV, &reexported)
+		expected := strings.Replace(testTable[1].expected, "2,1", "2,2", 1)
+		if reexported.String() != expected {
+			t.Errorf("Expected\n---\n%s\n---\nbut found\n---\n%s\n---\nafter importing %s", expected, reexported.String(), format)
+		}
+		if format != FormatCSV && format != FormatMARC && imported.books["9781617293092"].Extras["publisher"] != "Manning" {
+			t.Errorf("Expected the publisher to be imported from %s but found %v", format,
+				imported.books["9781617293092"].Extras)
+		}
+	}
+}
+
+func TestMARCExtras(t *testing.T) {
+	library := NewFancyLibrary(1)
+	input := "=001  ocm123\n=020  \\\\$a9781617293092\n=245  10$aLearn {dollar}GOPATH\n=260  \\\\$aShelter Island :$bManning\n"
+	if report, err := library.Import(FormatMARC, strings.NewReader(input)); err != nil || report.Failed != 0 {
+		t.Fatalf("Expected the record to be imported but found %+v, %v", report, err)
+	}
+	book := library.books["9781617293092"]
+	if book.Title != "Learn $GOPATH" || len(book.Extras) != 1 || book.Extras["marc260"] != `\\$aShelter Island :$bManning` {
+		t.Fatalf("Expected the title and the publication field but found %+v", book)
+	}
+
+	var output bytes.Buffer
+	library.Export(FormatMARC, &output)
+	expected := "=020  \\\\$a9781617293092\n=245  10$aLearn {dollar}GOPATH\n=999  \\\\$c1$d1\n=260  \\\\$aShelter Island :$bManning\n"
+	if output.String() != expected {
+		t.Errorf("Expected\n---\n%s\n---\nbut found\n---\n%s\n---\n", expected, output.String())
+	}
+}
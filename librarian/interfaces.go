@@ -1,17 +1,20 @@
 package librarian
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type Library interface {
 
 	// Добавя книга от json
 	// Oтговаря с общия брой копия в библиотеката (не само наличните).
-	// Aко са повече от 4 - връща грешка
+	// Aко са повече от лимита за копия (4 по подразбиране) - връща грешка
 	AddBookJSON(data []byte) (int, error)
 
 	// Добавя книга от xml
 	// Oтговаря с общия брой копия в библиотеката (не само наличните).
-	// Ако са повече от 4 - връщаме грешка
+	// Ако са повече от лимита за копия (4 по подразбиране) - връщаме грешка
 	AddBookXML(data []byte) (int, error)
 
 	// Ангажира свободен "библиотекар" да ни обработва заявките.
@@ -29,6 +32,19 @@ type LibraryRequest interface {
 	// 1 - Borrow book
 	// 2 - Return book
 	// 3 - Get availability information about book
+	// 4 - List all books
+	// 5 - Search books by title (LibrarySearchRequest)
+	// 6 - Search books by author (LibrarySearchRequest)
+	// 7 - List books without available copies
+	// 8 - List the loans of a member (LibraryMemberRequest)
+	// 9 - List the overdue loans of a member (LibraryMemberRequest)
+	// 10 - Reserve book (LibraryMemberRequest)
+	// 11 - Cancel the reservation of a book (LibraryMemberRequest)
+	// 12 - Get the place in the queue for a book (LibraryMemberRequest)
+	// 13 - Borrow and return many books, all of them or none (LibraryBatchRequest)
+	// 14 - Search books by genre (LibrarySearchRequest)
+	// 15 - Renew the loan of a book, the response has the new due date (LibraryMemberRequest)
+	// 16 - Add a copy of a book, the response has the book with its copies (LibraryAddRequest)
 	GetType() int
 
 	// Връща isbn на книгата, за която се отнася Request-a
@@ -40,10 +56,91 @@ type LibraryResponse interface {
 	// Aко книгата не съществува първият резултат е nil.
 	// Връща се и подобаващa грешка (виж по-долу) - ако такава е възникнала.
 	// Когато се е резултат на заявка от тип 2 (Return book) - не е нужно да я закачаме към отговора.
+	// Книгата е *Book - с жанра, страниците, оценките и останалите полета, с които е добавена.
 	GetBook() (fmt.Stringer, error)
 
 	// available - Колко наличности от книгата имаме останали след изпълнението на заявката.
 	// Тоест, ако сме имали 3 копия от Х и това е отговор на Take заявка - тук ще има 2.
-	// registered - Колко копия от тази книга има регистрирани в библиотеката (макс 4).
+	// registered - Колко копия от тази книга има регистрирани в библиотеката (най-много лимита за копия).
 	GetAvailability() (available int, registered int)
 }
+
+// Заявка, която търси в каталога - по част от заглавието или от името на автора
+type LibrarySearchRequest interface {
+	LibraryRequest
+
+	// Връща текста, който търсим. Малките и главните букви не се различават
+	GetQuery() string
+}
+
+// Отговор на заявка, която връща много книги
+type LibraryBooksResponse interface {
+	LibraryResponse
+
+	// Връща намерените книги, подредени по заглавие.
+	// Ако няма такива - празен списък без грешка
+	GetBooks() ([]fmt.Stringer, error)
+}
+
+// Заявка от името на читател на библиотеката
+type LibraryMemberRequest interface {
+	LibraryRequest
+
+	// Връща номера на читателя.
+	// Празен номер означава заявка без читател - тогава заемането не се записва
+	GetMemberID() string
+}
+
+// Отговор на заявка на читател за заемане или подновяване на книга
+type LibraryLoanResponse interface {
+	LibraryResponse
+
+	// Връща до кога трябва да бъде върната книгата
+	GetDueDate() time.Time
+}
+
+// Отговор на заявка, която връща заеманията на читател
+type LibraryLoansResponse interface {
+	LibraryResponse
+
+	// Връща заеманията, подредени по срок за връщане
+	GetLoans() ([]Loan, error)
+}
+
+// Отговор на заявка за резервация на книга
+type LibraryReservationResponse interface {
+	LibraryResponse
+
+	// Връща мястото на читателя в опашката за книгата, започвайки от 1
+	GetPosition() int
+
+	// Връща до кога е запазено копие за читателя.
+	// Нулево време, ако читателят още чака
+	GetHeldUntil() time.Time
+}
+
+// Заявка, която заема и връща много книги наведнъж.
+// Изпълнява се цялата или нищо от нея
+type LibraryBatchRequest interface {
+	LibraryRequest
+
+	// Връща заявките за заемане и връщане, в реда в който се изпълняват
+	GetRequests() []LibraryRequest
+}
+
+// Отговор на заявка с много заявки
+type LibraryBatchResponse interface {
+	LibraryResponse
+
+	// Връща отговорите в реда на заявките
+	// или грешката на първата неуспешна заявка
+	GetResponses() ([]LibraryResponse, error)
+}
+
+// Заявка за добавяне на копие от книга в библиотеката
+type LibraryAddRequest interface {
+	LibraryRequest
+
+	// Връща книгата, която добавяме
+	GetNewBook() *Book
+}
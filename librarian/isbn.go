@@ -0,0 +1,130 @@
+package librarian
+
+import "strings"
+
+// Returns the isbn without hyphens and spaces if it is a valid ISBN-10 or ISBN-13.
+// Returns an error of kind ErrInvalidISBN otherwise
+func NormalizeISBN(isbn string) (string, error) {
+	normalized, ok := normalizeISBN(isbn)
+	if !ok {
+		return "", &LibraryError{Kind: ErrInvalidISBN, ISBN: isbn}
+	}
+	return normalized, nil
+}
+
+// Returns the ISBN-13 form of a valid ISBN-10 or ISBN-13
+func ISBN13(isbn string) (string, error) {
+	normalized, err := NormalizeISBN(isbn)
+	if err != nil || len(normalized) == 13 {
+		return normalized, err
+	}
+	return toISBN13(normalized), nil
+}
+
+// Returns the ISBN-10 form of a valid ISBN-10 or ISBN-13.
+// Only the ISBN-13 starting with 978 have such a form
+func ISBN10(isbn string) (string, error) {
+	normalized, err := NormalizeISBN(isbn)
+	if err != nil || len(normalized) == 10 {
+		return normalized, err
+	}
+	if converted, ok := toISBN10(normalized); ok {
+		return converted, nil
+	}
+	return "", &LibraryError{Kind: ErrInvalidISBN, ISBN: isbn}
+}
+
+// Removes the hyphens and the spaces and checks the check digit
+func normalizeISBN(isbn string) (string, bool) {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+	switch len(normalized) {
+	case 10:
+		for i, c := range normalized {
+			if (c < '0' || c > '9') && (c != 'X' || i != 9) {
+				return "", false
+			}
+		}
+		return normalized, isbn10Check(normalized[:9]) == normalized[9]
+	case 13:
+		for _, c := range normalized {
+			if c < '0' || c > '9' {
+				return "", false
+			}
+		}
+		prefix := normalized[:3]
+		return normalized, (prefix == "978" || prefix == "979") && isbn13Check(normalized[:12]) == normalized[12]
+	}
+	return "", false
+}
+
+// Returns the check digit for the first 9 digits of an ISBN-10
+func isbn10Check(digits string) byte {
+	sum := 0
+	for i, c := range digits {
+		sum += (10 - i) * int(c-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// Returns the check digit for the first 12 digits of an ISBN-13
+func isbn13Check(digits string) byte {
+	sum := 0
+	for i, c := range digits {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(c-'0')
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// Has to be called with a normalized ISBN-10
+func toISBN13(isbn string) string {
+	digits := "978" + isbn[:9]
+	return digits + string(isbn13Check(digits))
+}
+
+// Has to be called with a normalized ISBN-13
+func toISBN10(isbn string) (string, bool) {
+	if !strings.HasPrefix(isbn, "978") {
+		return "", false
+	}
+	digits := isbn[3:12]
+	return digits + string(isbn10Check(digits)), true
+}
+
+// Returns the forms of the isbn - normalized and converted to the other length,
+// or nothing if it is not valid
+func isbnForms(isbn string) []string {
+	normalized, ok := normalizeISBN(isbn)
+	if !ok {
+		return nil
+	}
+	if len(normalized) == 10 {
+		return []string{normalized, toISBN13(normalized)}
+	}
+	if converted, ok := toISBN10(normalized); ok {
+		return []string{normalized, converted}
+	}
+	return []string{normalized}
+}
+
+// Returns the isbn under which the book is in the library - the same isbn in another form
+// if the book was added with it, or isbn if the book is unknown
+// Has to be called while holding the mutex
+func (library *FancyLibrary) resolve(isbn string) string {
+	if _, ok := library.books[isbn]; ok {
+		return isbn
+	}
+	for _, form := range isbnForms(isbn) {
+		if _, ok := library.books[form]; ok {
+			return form
+		}
+	}
+	return isbn
+}
@@ -0,0 +1,68 @@
+package librarian
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeISBN(t *testing.T) {
+	testTable := []struct {
+		isbn   string
+		isbn10 string
+		isbn13 string
+	}{
+		{isbn: "0954540018", isbn10: "0954540018", isbn13: "9780954540012"},
+		{isbn: "0-9545-4001-8", isbn10: "0954540018", isbn13: "9780954540012"},
+		{isbn: "978-0-9545400-1-2", isbn10: "0954540018", isbn13: "9780954540012"},
+		{isbn: "080442957x", isbn10: "080442957X", isbn13: "9780804429573"},
+		{isbn: "978 1 61729 309 2", isbn10: "1617293091", isbn13: "9781617293092"},
+		// there is no ISBN-10 for 979
+		{isbn: "9791032305690", isbn10: "", isbn13: "9791032305690"},
+		{isbn: "0954540019", isbn10: "", isbn13: ""},
+		{isbn: "9781617293093", isbn10: "", isbn13: ""},
+		{isbn: "X954540018", isbn10: "", isbn13: ""},
+		{isbn: "IMNOTABOOK", isbn10: "", isbn13: ""},
+	}
+	for _, testCase := range testTable {
+		isbn10, err10 := ISBN10(testCase.isbn)
+		isbn13, err13 := ISBN13(testCase.isbn)
+		if isbn10 != testCase.isbn10 || isbn13 != testCase.isbn13 {
+			t.Errorf("Expected %q and %q for %s but found %q and %q",
+				testCase.isbn10, testCase.isbn13, testCase.isbn, isbn10, isbn13)
+		}
+		if (testCase.isbn10 == "") != errors.Is(err10, ErrInvalidISBN) ||
+			(testCase.isbn13 == "") != errors.Is(err13, ErrInvalidISBN) {
+			t.Errorf("Expected an error only for an invalid isbn but found %v and %v for %s",
+				err10, err13, testCase.isbn)
+		}
+	}
+}
+
+func TestISBNForms(t *testing.T) {
+	library := NewFancyLibrary(1, WithBookCopyLimit("9780954540012", 3))
+	for _, isbn := range []string{"0954540018", "0-9545-4001-8", "978-0-9545400-1-2"} {
+		if _, err := library.AddBookJSON([]byte(`{"isbn": "` + isbn + `", "title": "Who Said the Race is Over?"}`)); err != nil {
+			t.Errorf("There must not be an error for %s but found %s", isbn, err.Error())
+		}
+	}
+	_, err := library.AddBookJSON([]byte(`{"isbn": "9780954540012"}`))
+	if err == nil || err.Error() != "Има 3 копия на книга 0954540018" {
+		t.Errorf("Expected the copy limit of the book but found %v", err)
+	}
+	_, err = library.AddBookXML([]byte(`<book isbn="0954540019"><title>Typo</title></book>`))
+	var libraryErr *LibraryError
+	if !errors.As(err, &libraryErr) || libraryErr.Kind != ErrInvalidISBN || err.Error() != "Невалиден ISBN 0954540019" {
+		t.Errorf("Expected an invalid isbn but found %v", err)
+	}
+	if len(library.books) != 1 {
+		t.Errorf("Expected one book but found %v", library.books)
+	}
+
+	request, response := library.Hello()
+	defer close(request)
+	expectError(t, ask(request, response, &CoolLibraryRequest{"9780954540012", BorrowBook}), "")
+	available, registered := ask(request, response, &CoolLibraryRequest{"0-9545-4001-8", GetAvailability}).GetAvailability()
+	if available != 2 || registered != 3 {
+		t.Errorf("Expected 2 of 3 copies available but found %d of %d", available, registered)
+	}
+}
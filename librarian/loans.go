@@ -0,0 +1,319 @@
+package librarian
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Types of the requests about loans
+const (
+	// the loans of the member, or of all members if there is no member
+	ListLoans = iota + ListUnavailable + 1
+	// the loans after their due date, of the member or of all members
+	ListOverdue
+)
+
+// How long a member can keep a book, unless the policy says otherwise
+const LoanPeriod = 14 * 24 * time.Hour
+
+// Member - a reader registered in the library
+type Member struct {
+	ID    string
+	Name  string
+	loans []*Loan
+	// the fines for late returns which are not paid yet
+	fines int
+}
+
+// Loan - a copy of a book taken by a member
+type Loan struct {
+	Book     *Book
+	MemberID string
+	Borrowed time.Time
+	Due      time.Time
+	// how many times the loan has been renewed
+	Renewals int
+}
+
+// Returns a string representation of the loan
+func (loan Loan) String() string {
+	return loan.Book.String() + " - " + loan.MemberID + " до " + loan.Due.Format("2006-01-02")
+}
+
+// Registers a member of the library.
+// Returns an error if there is already a member with this id
+func (library *FancyLibrary) AddMember(id string, name string) error {
+	library.mutex.Lock()
+	defer library.mutex.Unlock()
+	return library.transaction(func(saved *snapshot) error {
+		saved.save(library, "", id)
+		if _, ok := library.members[id]; ok {
+			return library.fail(LibraryError{Kind: ErrMemberExists, MemberID: id})
+		}
+		library.members[id] = &Member{ID: id, Name: name}
+		return nil
+	})
+}
+
+// The type for the requests made by members
+type CoolMemberRequest struct {
+	isbn        string
+	memberID    string
+	requestType int
+}
+
+// Creates a request of the member. The isbn is not used by the requests about loans
+func NewMemberRequest(requestType int, isbn string, memberID string) *CoolMemberRequest {
+	return &CoolMemberRequest{isbn: isbn, memberID: memberID, requestType: requestType}
+}
+
+// Return the type of the request
+func (request *CoolMemberRequest) GetType() int {
+	return request.requestType
+}
+
+// Return the isbn of the book for which is the request
+func (request *CoolMemberRequest) GetISBN() string {
+	return request.isbn
+}
+
+// Return the id of the member making the request
+func (request *CoolMemberRequest) GetMemberID() string {
+	return request.memberID
+}
+
+// Returns the member making the request, "" if it is not a member request
+func memberID(request LibraryRequest) string {
+	if member, ok := request.(LibraryMemberRequest); ok {
+		return member.GetMemberID()
+	}
+	return ""
+}
+
+// The type for the responses to members borrowing a book
+type CoolLoanResponse struct {
+	CoolLibraryResponse
+	due time.Time
+}
+
+// Returns when the book has to be returned
+func (response *CoolLoanResponse) GetDueDate() time.Time {
+	return response.due
+}
+
+// The type for the responses with loans
+type CoolLoansResponse struct {
+	loans []Loan
+	err   error
+}
+
+// Returns the book of the first loan
+func (response *CoolLoansResponse) GetBook() (fmt.Stringer, error) {
+	if response.err != nil {
+		return nil, response.err
+	}
+	if len(response.loans) == 0 {
+		return nil, &LibraryError{Kind: ErrEmptyResponse}
+	}
+	return response.loans[0].Book, nil
+}
+
+// The loans are not about the availability of a book
+func (response *CoolLoansResponse) GetAvailability() (available int, registered int) {
+	return 0, 0
+}
+
+// Returns the loans ordered by due date
+func (response *CoolLoansResponse) GetLoans() ([]Loan, error) {
+	return response.loans, response.err
+}
+
+// Lends a copy of the book to the member, or to nobody known if member is nil.
+// The members blocked by the policy for their fines can not borrow
+// Has to be called while holding the mutex
+func (library *FancyLibrary) lend(isbn string, member *Member) CoolLibraryResponse {
+	book, ok := library.books[isbn]
+	if !ok {
+		return CoolLibraryResponse{err: library.fail(LibraryError{Kind: ErrUnknownBook, ISBN: isbn})}
+	}
+	if member != nil && library.policy.Blocked(member.fines) {
+		return CoolLibraryResponse{book: book, err: library.fail(
+			LibraryError{Kind: ErrFinesUnpaid, ISBN: isbn, MemberID: member.ID, Amount: member.fines})}
+	}
+	library.refreshHolds(book)
+	holding := member != nil && library.holdsCopy(isbn, member.ID)
+	// a loan is recorded only for a copy which is really there
+	if !holding && book.availableCount <= 0 {
+		return CoolLibraryResponse{book: book, err: library.fail(LibraryError{Kind: ErrNoCopiesAvailable, ISBN: isbn})}
+	}
+
+	if holding {
+		library.dropReservation(isbn, member.ID)
+		book.held -= 1
+	} else {
+		book.availableCount -= 1
+	}
+	if member != nil {
+		now := library.now()
+		member.loans = append(member.loans,
+			&Loan{Book: book, MemberID: member.ID, Borrowed: now, Due: now.Add(library.policy.LoanPeriod(book))})
+		book.lent += 1
+	}
+	return CoolLibraryResponse{book: book}
+}
+
+// Takes back a copy of the book from the member, or from anybody if member is nil.
+// A member can return only the books it holds. The copies held by members
+// can not be returned by anybody else. Nothing changes if there is an error.
+// The member is fined by the policy if the book is late
+// Has to be called while holding the mutex
+func (library *FancyLibrary) takeBack(isbn string, member *Member) CoolLibraryResponse {
+	book, ok := library.books[isbn]
+	if !ok {
+		return CoolLibraryResponse{err: library.fail(LibraryError{Kind: ErrUnknownBook, ISBN: isbn})}
+	}
+	library.refreshHolds(book)
+
+	index := -1
+	if member != nil {
+		index = member.loanIndex(isbn)
+		if index < 0 {
+			return CoolLibraryResponse{book: book,
+				err: library.fail(LibraryError{Kind: ErrNotBorrowed, ISBN: isbn, MemberID: member.ID})}
+		}
+	} else if book.lent > 0 && book.availableCount+book.held+book.lent >= book.registeredCount {
+		return CoolLibraryResponse{book: book, err: library.fail(LibraryError{Kind: ErrLentToMembers, ISBN: isbn})}
+	}
+	if book.availableCount+book.held >= book.registeredCount {
+		return CoolLibraryResponse{book: book, err: library.fail(LibraryError{Kind: ErrAllCopiesReturned, ISBN: isbn})}
+	}
+
+	if member != nil {
+		member.fines += library.policy.Fine(*member.loans[index], library.now())
+		member.loans = append(member.loans[:index], member.loans[index+1:]...)
+		book.lent -= 1
+	}
+	book.availableCount += 1
+	library.removeReturned(book)
+	library.refreshHolds(book)
+	return CoolLibraryResponse{book: book}
+}
+
+// Returns the position of the oldest loan of the book, -1 if the member does not have it
+func (member *Member) loanIndex(isbn string) int {
+	for i, loan := range member.loans {
+		if loan.Book.ISBN == isbn {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns the member with the id.
+// Returns nil without an error if id is ""
+// Has to be called while holding the mutex
+func (library *FancyLibrary) member(id string) (*Member, error) {
+	if id == "" {
+		return nil, nil
+	}
+	member, ok := library.members[id]
+	if !ok {
+		return nil, library.fail(LibraryError{Kind: ErrUnknownMember, MemberID: id})
+	}
+	return member, nil
+}
+
+// Lends a copy of the book to the member of the request, the loan is not recorded without a member.
+// The member gets the due date and a copy of the book with its counts after the loan
+func (librarian *Librarian) borrow(isbn string, memberID string) LibraryResponse {
+	library := librarian.library
+	library.mutex.Lock()
+	defer library.mutex.Unlock()
+	isbn = library.resolve(isbn)
+	return detach(library.commit(isbn, memberID, func() LibraryResponse {
+		return library.checkOut(isbn, memberID)
+	}))
+}
+
+// Takes back a copy of the book from the member of the request, or from anybody without a member.
+// The response has a copy of the book with its counts after the return
+func (librarian *Librarian) giveBack(isbn string, memberID string) LibraryResponse {
+	library := librarian.library
+	library.mutex.Lock()
+	defer library.mutex.Unlock()
+	isbn = library.resolve(isbn)
+	return detach(library.commit(isbn, memberID, func() LibraryResponse {
+		return library.checkIn(isbn, memberID)
+	}))
+}
+
+// Lends a copy of the book to the member, or to nobody known if memberID is ""
+// Has to be called while holding the mutex
+func (library *FancyLibrary) checkOut(isbn string, memberID string) LibraryResponse {
+	member, err := library.member(memberID)
+	if err != nil {
+		return &CoolLibraryResponse{err: err}
+	}
+	if member == nil {
+		response := library.lend(isbn, nil)
+		return &response
+	}
+	response := CoolLoanResponse{CoolLibraryResponse: library.lend(isbn, member)}
+	if response.err == nil {
+		response.due = member.loans[len(member.loans)-1].Due
+	}
+	return &response
+}
+
+// Takes back a copy of the book from the member, or from anybody if memberID is ""
+// Has to be called while holding the mutex
+func (library *FancyLibrary) checkIn(isbn string, memberID string) LibraryResponse {
+	member, err := library.member(memberID)
+	if err != nil {
+		return &CoolLibraryResponse{err: err}
+	}
+	response := library.takeBack(isbn, member)
+	return &response
+}
+
+// Returns the loans of the member, or of all the members if memberID is "".
+// With overdue only the loans after their due date are returned
+func (librarian *Librarian) listLoans(memberID string, overdue bool) CoolLoansResponse {
+	library := librarian.library
+	library.mutex.Lock()
+	defer library.mutex.Unlock()
+
+	var members []*Member
+	if memberID != "" {
+		member, err := library.member(memberID)
+		if err != nil {
+			return CoolLoansResponse{err: err}
+		}
+		members = []*Member{member}
+	} else {
+		for _, member := range library.members {
+			members = append(members, member)
+		}
+	}
+
+	now := library.now()
+	loans := make([]Loan, 0)
+	for _, member := range members {
+		for _, loan := range member.loans {
+			if !overdue || now.After(loan.Due) {
+				// the book of the library changes after the request
+				listed, book := *loan, *loan.Book
+				listed.Book = &book
+				loans = append(loans, listed)
+			}
+		}
+	}
+	sort.Slice(loans, func(i, j int) bool {
+		if !loans[i].Due.Equal(loans[j].Due) {
+			return loans[i].Due.Before(loans[j].Due)
+		}
+		return loans[i].MemberID < loans[j].MemberID
+	})
+	return CoolLoansResponse{loans: loans}
+}
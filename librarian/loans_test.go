@@ -0,0 +1,153 @@
+package librarian
+
+import (
+	"testing"
+	"time"
+)
+
+// Sends the request and returns the response
+func ask(request chan<- LibraryRequest, response <-chan LibraryResponse, message LibraryRequest) LibraryResponse {
+	request <- message
+	return <-response
+}
+
+// Checks that the response has the expected error, "" for none
+func expectError(t *testing.T, message LibraryResponse, expected string) {
+	t.Helper()
+	_, err := message.GetBook()
+	found := ""
+	if err != nil {
+		found = err.Error()
+	}
+	if found != expected {
+		t.Errorf("Expected error\n---\n%s\n---\nbut found\n---\n%s\n---\n", expected, found)
+	}
+}
+
+// Returns the loans in the response
+func loansOf(t *testing.T, message LibraryResponse) []Loan {
+	t.Helper()
+	loans, err := message.(LibraryLoansResponse).GetLoans()
+	if err != nil {
+		t.Fatalf("There must not be an error but found %s", err.Error())
+	}
+	return loans
+}
+
+func TestMemberLoans(t *testing.T) {
+	library := NewFancyLibrary(2)
+	start := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	now := start
+	library.now = func() time.Time { return now }
+	library.AddBookJSON([]byte(catalogueBooks[1]))
+	library.AddMember("alice", "Alice")
+	library.AddMember("bob", "Bob")
+	if err := library.AddMember("bob", "Bob"); err == nil {
+		t.Errorf("Expected an error for the second bob")
+	}
+
+	request, response := library.Hello()
+	defer close(request)
+
+	borrowed := ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "alice"))
+	expectError(t, borrowed, "")
+	if due := borrowed.(LibraryLoanResponse).GetDueDate(); !due.Equal(start.Add(LoanPeriod)) {
+		t.Errorf("Expected due date %s but found %s", start.Add(LoanPeriod), due)
+	}
+
+	expectError(t, ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "bob")),
+		"Няма наличност на книга 0954540018")
+	expectError(t, ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "carol")),
+		"Непознат читател carol")
+	expectError(t, ask(request, response, NewMemberRequest(ReturnBook, "0954540018", "bob")),
+		"Читател bob няма книга 0954540018")
+	expectError(t, ask(request, response, &CoolLibraryRequest{"0954540018", ReturnBook}),
+		"Копията на книга 0954540018 са при читатели")
+
+	availability := ask(request, response, &CoolLibraryRequest{"0954540018", GetAvailability})
+	if available, registered := availability.GetAvailability(); available != 0 || registered != 1 {
+		t.Errorf("Expected 0 of 1 copies available but found %d of %d", available, registered)
+	}
+
+	loans := loansOf(t, ask(request, response, NewMemberRequest(ListLoans, "", "alice")))
+	if len(loans) != 1 || loans[0].Book.ISBN != "0954540018" || !loans[0].Borrowed.Equal(start) {
+		t.Errorf("Expected the loan of alice but found %v", loans)
+	}
+	if loans := loansOf(t, ask(request, response, NewMemberRequest(ListLoans, "", "bob"))); len(loans) != 0 {
+		t.Errorf("Expected no loans of bob but found %v", loans)
+	}
+	if loans := loansOf(t, ask(request, response, NewMemberRequest(ListOverdue, "", "alice"))); len(loans) != 0 {
+		t.Errorf("Expected no overdue loans but found %v", loans)
+	}
+
+	now = start.Add(LoanPeriod + time.Hour)
+	overdue := loansOf(t, ask(request, response, NewMemberRequest(ListOverdue, "", "")))
+	expected := "[0954540018] Who Said the Race is Over? от Anno Birkin - alice до 2020-03-15"
+	if len(overdue) != 1 || overdue[0].String() != expected {
+		t.Errorf("Expected\n---\n%s\n---\nbut found\n---\n%v\n---\n", expected, overdue)
+	}
+
+	expectError(t, ask(request, response, NewMemberRequest(ReturnBook, "0954540018", "alice")), "")
+	if loans := loansOf(t, ask(request, response, NewMemberRequest(ListLoans, "", ""))); len(loans) != 0 {
+		t.Errorf("Expected no loans after the return but found %v", loans)
+	}
+	availability = ask(request, response, &CoolLibraryRequest{"0954540018", GetAvailability})
+	if available, registered := availability.GetAvailability(); available != 1 || registered != 1 {
+		t.Errorf("Expected 1 of 1 copies available but found %d of %d", available, registered)
+	}
+}
+
+func TestAnonymousAndMemberLoans(t *testing.T) {
+	library := NewFancyLibrary(1)
+	library.AddBookJSON([]byte(catalogueBooks[0]))
+	library.AddBookJSON([]byte(catalogueBooks[0]))
+	library.AddMember("alice", "Alice")
+
+	request, response := library.Hello()
+	defer close(request)
+
+	expectError(t, ask(request, response, &CoolLibraryRequest{"9781617293092", BorrowBook}), "")
+	expectError(t, ask(request, response, NewMemberRequest(BorrowBook, "9781617293092", "alice")), "")
+	// the copy without a member can be returned, the one of alice can not
+	expectError(t, ask(request, response, &CoolLibraryRequest{"9781617293092", ReturnBook}), "")
+	expectError(t, ask(request, response, &CoolLibraryRequest{"9781617293092", ReturnBook}),
+		"Копията на книга 9781617293092 са при читатели")
+	expectError(t, ask(request, response, NewMemberRequest(ReturnBook, "9781617293092", "alice")), "")
+}
+
+func TestListedLoansAreCopies(t *testing.T) {
+	library := NewFancyLibrary(2)
+	library.AddBookJSON([]byte(catalogueBooks[0]))
+	library.AddBookJSON([]byte(catalogueBooks[0]))
+	library.AddMember("alice", "Alice")
+
+	request, response := library.Hello()
+	defer close(request)
+	expectError(t, ask(request, response, NewMemberRequest(BorrowBook, "9781617293092", "alice")), "")
+	loans := loansOf(t, ask(request, response, NewMemberRequest(ListLoans, "", "alice")))
+
+	// another librarian changes the book while the loans are read
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		other, answers := library.Hello()
+		defer close(other)
+		for i := 0; i < 50; i++ {
+			ask(other, answers, &CoolLibraryRequest{"9781617293092", BorrowBook})
+			ask(other, answers, &CoolLibraryRequest{"9781617293092", ReturnBook})
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		if available := loans[0].Book.availableCount; available != 1 {
+			t.Fatalf("Expected the listed book to keep 1 available copy but found %d", available)
+		}
+		_ = loans[0].String()
+	}
+	<-done
+
+	loans[0].Book.Title = "Changed"
+	availability := ask(request, response, &CoolLibraryRequest{"9781617293092", GetAvailability})
+	if book, _ := availability.GetBook(); book.(*Book).Title == "Changed" {
+		t.Errorf("Expected the listed loans not to change the catalogue")
+	}
+}
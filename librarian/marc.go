@@ -0,0 +1,216 @@
+package librarian
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The MARC-like format is the text form of MARC records used by catalogue tools.
+// Each field of a record is on a line of its own - "=" with the tag, two spaces,
+// two indicators, "\" for a blank one, and then the subfields, each one starting
+// with "$" and its code. The records are separated with empty lines:
+//
+//	=020  \\$a0954540018
+//	=100  1\$aBirkin, Anno
+//	=245  10$aWho Said the Race is Over?
+//	=300  \\$a80 p.
+//	=655  \\$apoetry
+//	=999  \\$c2$d1$r5;4
+//
+// The local field 999 has the copies ($c), the available copies ($d)
+// and the ratings ($r, separated with ";"). The other fields of a record
+// go to the extras of the book as they are written, named "marc" and the tag,
+// and they are written back on export
+const (
+	marcISBN    = "020"
+	marcAuthor  = "100"
+	marcTitle   = "245"
+	marcPages   = "300"
+	marcSubject = "650"
+	marcGenre   = "655"
+	marcCopies  = "999"
+)
+
+// The prefix of the names of the extras with other MARC fields
+const marcExtra = "marc"
+
+// "$" starts a subfield, so it is written as {dollar} in the values
+var (
+	marcEscaper   = strings.NewReplacer("$", "{dollar}", "\r", " ", "\n", " ")
+	marcUnescaper = strings.NewReplacer("{dollar}", "$")
+)
+
+// A field of a MARC-like record
+type marcField struct {
+	tag        string
+	indicators string
+	data       string
+}
+
+// Returns if the field is the leader or a control field, which are about the record and not about the book
+func (field marcField) control() bool {
+	return field.tag == "LDR" || field.tag < "010"
+}
+
+// Returns the value of the first subfield with the code, "" if there is none
+func (field marcField) subfield(code byte) string {
+	for _, subfield := range strings.Split(field.data, "$")[1:] {
+		if len(subfield) > 0 && subfield[0] == code {
+			return strings.TrimSpace(marcUnescaper.Replace(subfield[1:]))
+		}
+	}
+	return ""
+}
+
+// Reads a record after each empty line
+func importMARC(reader io.Reader, add func(*Book, error)) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1024*1024)
+	var fields []marcField
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			if len(fields) > 0 {
+				add(readMARCBook(fields))
+				fields = nil
+			}
+			continue
+		}
+		if len(text) < 4 || text[0] != '=' {
+			return fmt.Errorf("line %d: expected a field starting with =tag but found %q", line, text)
+		}
+		field := marcField{tag: text[1:4], data: strings.TrimPrefix(text[4:], "  ")}
+		// the control fields have no indicators
+		if !field.control() && len(field.data) >= 2 {
+			field.indicators, field.data = field.data[:2], field.data[2:]
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) > 0 {
+		add(readMARCBook(fields))
+	}
+	return scanner.Err()
+}
+
+func readMARCBook(fields []marcField) (*Book, error) {
+	book := new(Book)
+	for _, field := range fields {
+		switch field.tag {
+		case marcISBN:
+			// the isbn may be followed by a qualifier - 0954540018 (pbk.)
+			if isbn := strings.Fields(field.subfield('a')); len(isbn) > 0 {
+				book.ISBN = isbn[0]
+			}
+		case marcAuthor:
+			name := strings.TrimRight(field.subfield('a'), ",.")
+			if comma := strings.Index(name, ","); comma >= 0 {
+				book.Author.LastName = strings.TrimSpace(name[:comma])
+				book.Author.FirstName = strings.TrimSpace(name[comma+1:])
+			} else {
+				book.Author.LastName = name
+			}
+		case marcTitle:
+			book.Title = strings.TrimRight(field.subfield('a'), " /:;")
+		case marcPages:
+			extent := field.subfield('a')
+			digits := strings.IndexFunc(extent, func(r rune) bool { return r < '0' || r > '9' })
+			if digits < 0 {
+				digits = len(extent)
+			}
+			pages, err := strconv.Atoi(extent[:digits])
+			if err != nil {
+				return nil, fmt.Errorf("expected the pages in %q", extent)
+			}
+			book.Pages = pages
+		case marcGenre, marcSubject:
+			if book.Genre == "" {
+				book.Genre = strings.TrimRight(field.subfield('a'), ".")
+			}
+		case marcCopies:
+			for code, name := range map[byte]string{'c': "copies", 'd': "available"} {
+				if value := field.subfield(code); value != "" {
+					if book.Extras == nil {
+						book.Extras = make(map[string]interface{})
+					}
+					book.Extras[name] = value
+				}
+			}
+			for _, rating := range strings.FieldsFunc(field.subfield('r'), func(r rune) bool { return r == ';' }) {
+				number, err := strconv.Atoi(strings.TrimSpace(rating))
+				if err != nil {
+					return nil, err
+				}
+				book.Ratings = append(book.Ratings, number)
+			}
+		default:
+			if field.control() {
+				continue
+			}
+			if book.Extras == nil {
+				book.Extras = make(map[string]interface{})
+			}
+			book.Extras[marcExtra+field.tag] = field.indicators + field.data
+		}
+	}
+	return book, nil
+}
+
+func exportMARC(writer io.Writer, books []exportedBook) error {
+	buffered := bufio.NewWriter(writer)
+	field := func(tag string, indicators string, subfields ...string) {
+		buffered.WriteString("=" + tag + "  " + indicators)
+		for i := 0; i+1 < len(subfields); i += 2 {
+			buffered.WriteString("$" + subfields[i] + marcEscaper.Replace(subfields[i+1]))
+		}
+		buffered.WriteString("\n")
+	}
+	for i, exported := range books {
+		book := exported.book
+		if i > 0 {
+			buffered.WriteString("\n")
+		}
+		field(marcISBN, `\\`, "a", book.ISBN)
+		author := book.Author.LastName
+		if book.Author.FirstName != "" {
+			author += ", " + book.Author.FirstName
+		}
+		if author != "" {
+			field(marcAuthor, `1\`, "a", author)
+		}
+		field(marcTitle, "10", "a", book.Title)
+		if book.Pages != 0 {
+			field(marcPages, `\\`, "a", strconv.Itoa(book.Pages)+" p.")
+		}
+		if book.Genre != "" {
+			field(marcGenre, `\\`, "a", book.Genre)
+		}
+		holdings := []string{"c", strconv.Itoa(exported.registered), "d", strconv.Itoa(exported.available)}
+		if len(book.Ratings) > 0 {
+			ratings := make([]string, len(book.Ratings))
+			for i, rating := range book.Ratings {
+				ratings[i] = strconv.Itoa(rating)
+			}
+			holdings = append(holdings, "r", strings.Join(ratings, ";"))
+		}
+		field(marcCopies, `\\`, holdings...)
+
+		names := make([]string, 0, len(book.Extras))
+		for name := range book.Extras {
+			if value, ok := book.Extras[name].(string); ok && len(name) == len(marcExtra)+3 &&
+				strings.HasPrefix(name, marcExtra) && !strings.ContainsAny(value, "\r\n") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			buffered.WriteString("=" + name[len(marcExtra):] + "  " + book.Extras[name].(string) + "\n")
+		}
+	}
+	return buffered.Flush()
+}
@@ -0,0 +1,79 @@
+package librarian
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+)
+
+// The JSON fields of Book, the others go to its Extras
+var bookFields = map[string]bool{
+	"isbn": true, "title": true, "author": true, "genre": true, "pages": true, "ratings": true,
+}
+
+// Reads the book from JSON, keeping the unknown fields in Extras
+func (book *Book) UnmarshalJSON(data []byte) error {
+	type plain Book
+	if err := json.Unmarshal(data, (*plain)(book)); err != nil {
+		return err
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for name, value := range fields {
+		// the names of the fields are matched like encoding/json does it
+		if bookFields[strings.ToLower(name)] {
+			continue
+		}
+		if book.Extras == nil {
+			book.Extras = make(map[string]interface{})
+		}
+		book.Extras[name] = value
+	}
+	return nil
+}
+
+// Writes the book as JSON with its Extras next to the other fields
+func (book Book) MarshalJSON() ([]byte, error) {
+	type plain Book
+	data, err := json.Marshal(plain(book))
+	if err != nil || len(book.Extras) == 0 {
+		return data, err
+	}
+	fields := make(map[string]interface{}, len(book.Extras))
+	for name, value := range book.Extras {
+		fields[name] = value
+	}
+	known := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &known); err != nil {
+		return nil, err
+	}
+	for name, value := range known {
+		fields[name] = value
+	}
+	return json.Marshal(fields)
+}
+
+// Reads the book from XML, keeping the text of the unknown elements in Extras
+func (book *Book) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	type plain Book
+	element := struct {
+		plain
+		Others []struct {
+			XMLName xml.Name
+			Value   string `xml:",innerxml"`
+		} `xml:",any"`
+	}{}
+	if err := decoder.DecodeElement(&element, &start); err != nil {
+		return err
+	}
+	*book = Book(element.plain)
+	for _, other := range element.Others {
+		if book.Extras == nil {
+			book.Extras = make(map[string]interface{})
+		}
+		book.Extras[other.XMLName.Local] = strings.TrimSpace(other.Value)
+	}
+	return nil
+}
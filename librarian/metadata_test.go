@@ -0,0 +1,76 @@
+package librarian
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestBookMetadata(t *testing.T) {
+	library := NewFancyLibrary(1)
+	library.AddBookJSON([]byte(`{
+		"isbn": "0954540018",
+		"title": "Who Said the Race is Over?",
+		"author": {"first_name": "Anno", "last_name": "Birkin"},
+		"genre": "poetry",
+		"pages": 80,
+		"ratings": [5, 4, 4, 5, 3],
+		"publisher": "Birkin Books",
+		"editions": [1, 2]
+	}`))
+	library.AddBookXML([]byte(`
+		<book isbn="9781617293092">
+			<title>Learn Go</title>
+			<author><first_name>Nathan</first_name><last_name>Youngman</last_name></author>
+			<genre>Programming</genre>
+			<pages>464</pages>
+			<ratings><rating>4</rating><rating>5</rating></ratings>
+			<publisher>Manning</publisher>
+		</book>`))
+
+	request, response := library.Hello()
+	defer close(request)
+
+	testTable := []struct {
+		isbn     string
+		expected string
+	}{
+		{isbn: "0954540018",
+			expected: `poetry 80 [5 4 4 5 3] map[editions:[1 2] publisher:Birkin Books]`},
+		{isbn: "9781617293092",
+			expected: `Programming 464 [4 5] map[publisher:Manning]`},
+	}
+	for _, testCase := range testTable {
+		stringer, err := ask(request, response, &CoolLibraryRequest{testCase.isbn, GetAvailability}).GetBook()
+		if err != nil {
+			t.Fatalf("There must not be an error but found %s", err.Error())
+		}
+		book := stringer.(*Book)
+		found := fmt.Sprint(book.Genre, " ", book.Pages, " ", book.Ratings, " ", book.Extras)
+		if found != testCase.expected {
+			t.Errorf("Expected\n---\n%s\n---\nbut found\n---\n%s\n---\n", testCase.expected, found)
+		}
+	}
+
+	found := bookStrings(t, ask(request, response, NewCatalogueRequest(SearchByGenre, "PROGRAMMING")))
+	if fmt.Sprint(found) != "[[9781617293092] Learn Go от Nathan Youngman]" {
+		t.Errorf("Expected the programming books but found %v", found)
+	}
+	books, _ := ask(request, response, NewCatalogueRequest(SearchByGenre, "poetry")).(LibraryBooksResponse).GetBooks()
+	if len(books) != 1 || books[0].(*Book).Extras["publisher"] != "Birkin Books" {
+		t.Errorf("Expected the poetry book with its publisher but found %v", books)
+	}
+}
+
+func TestBookJSON(t *testing.T) {
+	book := Book{}
+	// the fields are ordered by name when there are extras
+	data := `{"author":{"first_name":"A","last_name":"B"},"genre":"g","isbn":"1","note":{"x":true},"title":"T"}`
+	if err := json.Unmarshal([]byte(data), &book); err != nil {
+		t.Fatalf("There must not be an error but found %s", err.Error())
+	}
+	written, _ := json.Marshal(book)
+	if string(written) != data {
+		t.Errorf("Expected\n---\n%s\n---\nbut found\n---\n%s\n---\n", data, written)
+	}
+}
@@ -0,0 +1,120 @@
+package librarian
+
+import (
+	"strings"
+	"time"
+)
+
+// Policy - the rules of the library for the loans of its members
+type Policy interface {
+	// Returns how long a member can keep the book
+	LoanPeriod(book *Book) time.Duration
+	// Returns how many times a loan of the book can be renewed
+	MaxRenewals(book *Book) int
+	// Returns the fine for the loan if it is returned at the time, 0 if it is not late
+	Fine(loan Loan, returned time.Time) int
+	// Returns whether a member owing the fines can not borrow books
+	Blocked(fines int) bool
+}
+
+// StandardPolicy - a policy with a loan period for each genre and daily fines.
+// The fines are in the smallest unit of the currency
+type StandardPolicy struct {
+	// the loan period of the books without a period for their genre
+	Period time.Duration
+	// the loan periods by genre, the genres are matched without case
+	GenrePeriods map[string]time.Duration
+	Renewals     int
+	// the fine for each day after the due date, a started day is a whole day
+	DailyFine int
+	// the most a loan can be fined, 0 for no limit
+	FineCap int
+	// the members owing at least that much can not borrow, 0 to block for any fine
+	BlockAt int
+}
+
+// The policy of the library unless it is created with another.
+// There are no fines, so nobody is blocked
+var DefaultPolicy = StandardPolicy{Period: LoanPeriod, Renewals: 2}
+
+// Returns the period for the genre of the book
+func (policy StandardPolicy) LoanPeriod(book *Book) time.Duration {
+	for genre, period := range policy.GenrePeriods {
+		if strings.EqualFold(genre, book.Genre) {
+			return period
+		}
+	}
+	return policy.Period
+}
+
+// Every book can be renewed the same number of times
+func (policy StandardPolicy) MaxRenewals(book *Book) int {
+	return policy.Renewals
+}
+
+// Returns the daily fine for each started day after the due date, up to the cap
+func (policy StandardPolicy) Fine(loan Loan, returned time.Time) int {
+	late := returned.Sub(loan.Due)
+	if late <= 0 {
+		return 0
+	}
+	days := int((late + 24*time.Hour - 1) / (24 * time.Hour))
+	fine := days * policy.DailyFine
+	if policy.FineCap > 0 && fine > policy.FineCap {
+		fine = policy.FineCap
+	}
+	return fine
+}
+
+// Blocks the members owing at least BlockAt
+func (policy StandardPolicy) Blocked(fines int) bool {
+	return fines > 0 && fines >= policy.BlockAt
+}
+
+// Sets the policy for the loans of the members
+func WithPolicy(policy Policy) Option {
+	return func(library *FancyLibrary) {
+		library.policy = policy
+	}
+}
+
+// Sets the clock of the library, so the time can be moved in tests
+func WithClock(now func() time.Time) Option {
+	return func(library *FancyLibrary) {
+		library.now = now
+	}
+}
+
+// Returns the unpaid fines of the member
+func (library *FancyLibrary) Fines(memberID string) (int, error) {
+	library.mutex.Lock()
+	defer library.mutex.Unlock()
+	member, ok := library.members[memberID]
+	if !ok {
+		return 0, library.fail(LibraryError{Kind: ErrUnknownMember, MemberID: memberID})
+	}
+	return member.fines, nil
+}
+
+// Pays a part of the fines of the member.
+// Returns how much the member still owes.
+// Return an error if the amount is not positive or is more than the fines
+func (library *FancyLibrary) PayFine(memberID string, amount int) (int, error) {
+	library.mutex.Lock()
+	defer library.mutex.Unlock()
+	var left int
+	err := library.transaction(func(saved *snapshot) error {
+		saved.save(library, "", memberID)
+		member, ok := library.members[memberID]
+		if !ok {
+			return library.fail(LibraryError{Kind: ErrUnknownMember, MemberID: memberID})
+		}
+		if amount <= 0 || amount > member.fines {
+			return library.fail(LibraryError{Kind: ErrInvalidRequest, MemberID: memberID})
+		}
+		member.fines -= amount
+		left = member.fines
+		return nil
+	})
+	return left, err
+}
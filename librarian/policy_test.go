@@ -0,0 +1,128 @@
+package librarian
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStandardPolicy(t *testing.T) {
+	policy := StandardPolicy{Period: LoanPeriod, GenrePeriods: map[string]time.Duration{"Reference": 24 * time.Hour},
+		DailyFine: 50, FineCap: 300, BlockAt: 100}
+	due := time.Date(2020, 3, 15, 10, 0, 0, 0, time.UTC)
+
+	if period := policy.LoanPeriod(&Book{Genre: "reference"}); period != 24*time.Hour {
+		t.Errorf("Expected period %s for reference books but found %s", 24*time.Hour, period)
+	}
+	if period := policy.LoanPeriod(&Book{Genre: "Poetry"}); period != LoanPeriod {
+		t.Errorf("Expected period %s for other books but found %s", LoanPeriod, period)
+	}
+
+	testTable := []struct {
+		returned time.Time
+		fine     int
+	}{
+		{returned: due.Add(-time.Hour), fine: 0},
+		{returned: due, fine: 0},
+		{returned: due.Add(time.Minute), fine: 50},
+		{returned: due.Add(24 * time.Hour), fine: 50},
+		{returned: due.Add(50 * time.Hour), fine: 150},
+		{returned: due.Add(30 * 24 * time.Hour), fine: 300},
+	}
+	for _, testCase := range testTable {
+		if fine := policy.Fine(Loan{Due: due}, testCase.returned); fine != testCase.fine {
+			t.Errorf("Expected fine %d at %s but found %d", testCase.fine, testCase.returned, fine)
+		}
+	}
+
+	if policy.Blocked(50) || !policy.Blocked(100) {
+		t.Errorf("Expected only the members owing at least 100 to be blocked")
+	}
+	if (StandardPolicy{}).Blocked(0) || !(StandardPolicy{}).Blocked(1) {
+		t.Errorf("Expected any fine to block without BlockAt")
+	}
+}
+
+func TestFinesAndBlocking(t *testing.T) {
+	start := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	now := start
+	policy := StandardPolicy{Period: LoanPeriod, GenrePeriods: map[string]time.Duration{"programming": 7 * 24 * time.Hour},
+		DailyFine: 20, FineCap: 100, BlockAt: 60}
+	library := NewFancyLibrary(1, WithPolicy(policy), WithClock(func() time.Time { return now }))
+	library.AddBookJSON([]byte(`{"isbn": "9781617293092", "title": "Learn Go", "genre": "Programming",
+		"author": {"first_name": "Nathan", "last_name": "Youngman"}}`))
+	library.AddBookJSON([]byte(catalogueBooks[1]))
+	library.AddMember("alice", "Alice")
+
+	request, response := library.Hello()
+	defer close(request)
+
+	borrowed := ask(request, response, NewMemberRequest(BorrowBook, "9781617293092", "alice"))
+	expected := start.Add(7 * 24 * time.Hour)
+	if due := borrowed.(LibraryLoanResponse).GetDueDate(); !due.Equal(expected) {
+		t.Errorf("Expected due date %s for a programming book but found %s", expected, due)
+	}
+	borrowed = ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "alice"))
+	if due := borrowed.(LibraryLoanResponse).GetDueDate(); !due.Equal(start.Add(LoanPeriod)) {
+		t.Errorf("Expected due date %s but found %s", start.Add(LoanPeriod), due)
+	}
+
+	// two days late - a fine below the blocking one
+	now = expected.Add(36 * time.Hour)
+	expectError(t, ask(request, response, NewMemberRequest(ReturnBook, "9781617293092", "alice")), "")
+	if fines, _ := library.Fines("alice"); fines != 40 {
+		t.Errorf("Expected fines 40 but found %d", fines)
+	}
+	expectError(t, ask(request, response, NewMemberRequest(BorrowBook, "9781617293092", "alice")), "")
+
+	// much later - the fine is capped
+	now = start.Add(LoanPeriod + 20*24*time.Hour)
+	expectError(t, ask(request, response, NewMemberRequest(ReturnBook, "0954540018", "alice")), "")
+	if fines, _ := library.Fines("alice"); fines != 140 {
+		t.Errorf("Expected fines 140 but found %d", fines)
+	}
+
+	blocked := ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "alice"))
+	expectError(t, blocked, "Читател alice има неплатени глоби 140")
+	if _, err := blocked.GetBook(); !errors.Is(err, ErrFinesUnpaid) {
+		t.Errorf("Expected ErrFinesUnpaid but found %v", err)
+	}
+	// the books can still be returned and borrowed without a member
+	expectError(t, ask(request, response, &CoolLibraryRequest{"0954540018", BorrowBook}), "")
+	expectError(t, ask(request, response, &CoolLibraryRequest{"0954540018", ReturnBook}), "")
+
+	if _, err := library.PayFine("alice", 200); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Expected ErrInvalidRequest for paying too much but found %v", err)
+	}
+	if left, err := library.PayFine("alice", 100); err != nil || left != 40 {
+		t.Errorf("Expected 40 left but found %d, %v", left, err)
+	}
+	expectError(t, ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "alice")), "")
+	if _, err := library.Fines("bob"); !errors.Is(err, ErrUnknownMember) {
+		t.Errorf("Expected ErrUnknownMember but found %v", err)
+	}
+}
+
+func TestFinesAreStored(t *testing.T) {
+	start := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	now := start
+	storage := NewMemoryStorage()
+	options := []Option{WithPolicy(StandardPolicy{Period: LoanPeriod, DailyFine: 10}), WithClock(func() time.Time { return now })}
+	library, _ := OpenLibrary(1, storage, options...)
+	library.AddBookJSON([]byte(catalogueBooks[1]))
+	library.AddMember("alice", "Alice")
+
+	request, response := library.Hello()
+	ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "alice"))
+	now = start.Add(LoanPeriod + 24*time.Hour)
+	ask(request, response, NewMemberRequest(ReturnBook, "0954540018", "alice"))
+	close(request)
+
+	reopened, err := OpenLibrary(1, storage, options...)
+	if err != nil {
+		t.Fatalf("There must not be an error but found %s", err.Error())
+	}
+	if fines, _ := reopened.Fines("alice"); fines != 10 {
+		t.Errorf("Expected fines 10 after reopening but found %d", fines)
+	}
+}
@@ -0,0 +1,59 @@
+package librarian
+
+import (
+	"context"
+	"time"
+)
+
+// PoolStats - how many of the librarians are serving someone
+type PoolStats struct {
+	Busy int
+	Free int
+}
+
+// Sets how long a librarian waits for a request before it is released.
+// The requests after that are answered with ErrSessionExpired
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(library *FancyLibrary) {
+		library.idleTimeout = timeout
+	}
+}
+
+// Gets a free librarian like Hello, but gives up when the context is done
+// Returns the error of the context if it is done before a librarian is free
+func (library *FancyLibrary) HelloContext(ctx context.Context) (chan<- LibraryRequest, <-chan LibraryResponse, error) {
+	// The librarians can get up to 100 requests before someone reads the response
+	request := make(chan LibraryRequest, 100)
+	response := make(chan LibraryResponse, 100)
+	librarian := Librarian{request: request, response: response, library: library}
+	select {
+	case library.librarians <- librarian:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	librarian.serve()
+	return librarian.request, librarian.response, nil
+}
+
+// Returns how many librarians are busy and how many are free
+func (library *FancyLibrary) Stats() PoolStats {
+	busy := len(library.librarians)
+	return PoolStats{Busy: busy, Free: cap(library.librarians) - busy}
+}
+
+// Frees the place of a librarian who has finished
+func (library *FancyLibrary) release() {
+	select {
+	case <-library.librarians:
+	default:
+	}
+}
+
+// Answers the requests after the librarian has left with an error,
+// until the request channel is closed
+func (librarian *Librarian) expire() {
+	for range librarian.request {
+		librarian.response <- &CoolLibraryResponse{
+			err: librarian.library.fail(LibraryError{Kind: ErrSessionExpired})}
+	}
+}
@@ -0,0 +1,67 @@
+package librarian
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Waits until the library has the expected busy librarians
+func waitForStats(t *testing.T, library *FancyLibrary, expected PoolStats) {
+	t.Helper()
+	for i := 0; i < 100 && library.Stats() != expected; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats := library.Stats(); stats != expected {
+		t.Errorf("Expected %+v but found %+v", expected, stats)
+	}
+}
+
+func TestReleaseOnClose(t *testing.T) {
+	library := NewFancyLibrary(1)
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		request, response, err := library.HelloContext(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("Expected a free librarian but found %s", err.Error())
+		}
+		waitForStats(t, library, PoolStats{Busy: 1, Free: 0})
+		request <- NewCatalogueRequest(ListBooks, "")
+		<-response
+		close(request)
+		waitForStats(t, library, PoolStats{Busy: 0, Free: 1})
+	}
+}
+
+func TestHelloContext(t *testing.T) {
+	library := NewFancyLibrary(1)
+	request, _ := library.Hello()
+	defer close(request)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := library.HelloContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to be exceeded but found %v", err)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	library := NewFancyLibrary(1, WithIdleTimeout(20*time.Millisecond))
+	library.AddBookJSON([]byte(catalogueBooks[0]))
+	request, response := library.Hello()
+	defer close(request)
+
+	expectError(t, ask(request, response, &CoolLibraryRequest{"9781617293092", GetAvailability}), "")
+	waitForStats(t, library, PoolStats{Busy: 0, Free: 1})
+
+	_, err := ask(request, response, &CoolLibraryRequest{"9781617293092", GetAvailability}).GetBook()
+	if !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("Expected the session to be expired but found %v", err)
+	}
+
+	// somebody else gets the librarian
+	other, _ := library.Hello()
+	close(other)
+}
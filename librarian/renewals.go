@@ -0,0 +1,72 @@
+package librarian
+
+// Renews the loan of the member, so it is due a loan period later (LibraryMemberRequest)
+const Renew = SearchByGenre + 1
+
+// Extends the oldest loan of the book by the member.
+// The member gets the new due date with the book
+func (librarian *Librarian) renew(isbn string, memberID string) LibraryResponse {
+	library := librarian.library
+	library.mutex.Lock()
+	defer library.mutex.Unlock()
+	if memberID == "" {
+		return &CoolLibraryResponse{err: library.fail(LibraryError{Kind: ErrInvalidRequest, ISBN: isbn})}
+	}
+	isbn = library.resolve(isbn)
+	return detach(library.commit(isbn, memberID, func() LibraryResponse {
+		return library.renewLoan(isbn, memberID)
+	}))
+}
+
+// Extends the loan by the loan period of the policy, counting from now.
+// A loan can not be renewed while other members wait for the book,
+// more times than the policy allows or by a member blocked for its fines.
+// A late loan is fined as if it is returned. Nothing changes if there is an error
+// Has to be called while holding the mutex
+func (library *FancyLibrary) renewLoan(isbn string, memberID string) LibraryResponse {
+	member, err := library.member(memberID)
+	if err != nil {
+		return &CoolLibraryResponse{err: err}
+	}
+	book, ok := library.books[isbn]
+	if !ok {
+		return &CoolLibraryResponse{err: library.fail(LibraryError{Kind: ErrUnknownBook, ISBN: isbn})}
+	}
+	library.refreshHolds(book)
+
+	index := member.loanIndex(isbn)
+	if index < 0 {
+		return &CoolLibraryResponse{book: book,
+			err: library.fail(LibraryError{Kind: ErrNotBorrowed, ISBN: isbn, MemberID: memberID})}
+	}
+	loan := member.loans[index]
+	if library.othersWaiting(isbn, memberID) {
+		return &CoolLibraryResponse{book: book,
+			err: library.fail(LibraryError{Kind: ErrReservedByOthers, ISBN: isbn, MemberID: memberID})}
+	}
+	if limit := library.policy.MaxRenewals(book); loan.Renewals >= limit {
+		return &CoolLibraryResponse{book: book,
+			err: library.fail(LibraryError{Kind: ErrRenewalLimit, ISBN: isbn, MemberID: memberID, Limit: limit})}
+	}
+	if library.policy.Blocked(member.fines) {
+		return &CoolLibraryResponse{book: book, err: library.fail(
+			LibraryError{Kind: ErrFinesUnpaid, ISBN: isbn, MemberID: memberID, Amount: member.fines})}
+	}
+
+	now := library.now()
+	member.fines += library.policy.Fine(*loan, now)
+	loan.Due = now.Add(library.policy.LoanPeriod(book))
+	loan.Renewals += 1
+	return &CoolLoanResponse{CoolLibraryResponse: CoolLibraryResponse{book: book}, due: loan.Due}
+}
+
+// Returns if members other than the one with memberID have reserved the book
+// Has to be called while holding the mutex
+func (library *FancyLibrary) othersWaiting(isbn string, memberID string) bool {
+	for _, reservation := range library.holds[isbn] {
+		if reservation.MemberID != memberID {
+			return true
+		}
+	}
+	return false
+}
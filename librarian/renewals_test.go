@@ -0,0 +1,101 @@
+package librarian
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRenew(t *testing.T) {
+	start := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	now := start
+	policy := StandardPolicy{Period: LoanPeriod, Renewals: 2, DailyFine: 10}
+	library := NewFancyLibrary(1, WithPolicy(policy), WithClock(func() time.Time { return now }))
+	library.AddBookJSON([]byte(catalogueBooks[1]))
+	library.AddBookJSON([]byte(catalogueBooks[0]))
+	library.AddMember("alice", "Alice")
+	library.AddMember("bob", "Bob")
+
+	request, response := library.Hello()
+	defer close(request)
+
+	expectError(t, ask(request, response, NewMemberRequest(Renew, "0954540018", "alice")),
+		"Читател alice няма книга 0954540018")
+	ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "alice"))
+
+	now = start.Add(10 * 24 * time.Hour)
+	renewed := ask(request, response, NewMemberRequest(Renew, "9780954540012", "alice"))
+	expectError(t, renewed, "")
+	if due := renewed.(LibraryLoanResponse).GetDueDate(); !due.Equal(now.Add(LoanPeriod)) {
+		t.Errorf("Expected due date %s but found %s", now.Add(LoanPeriod), due)
+	}
+
+	// renewed after the due date - the days late are fined
+	now = now.Add(LoanPeriod + 24*time.Hour)
+	expectError(t, ask(request, response, NewMemberRequest(Renew, "0954540018", "alice")), "")
+	if fines, _ := library.Fines("alice"); fines != 10 {
+		t.Errorf("Expected fines 10 but found %d", fines)
+	}
+
+	limited := ask(request, response, NewMemberRequest(Renew, "0954540018", "alice"))
+	expectError(t, limited, "Книга 0954540018 е подновена 2 пъти")
+	if _, err := limited.GetBook(); !errors.Is(err, ErrRenewalLimit) {
+		t.Errorf("Expected ErrRenewalLimit but found %v", err)
+	}
+
+	loans := loansOf(t, ask(request, response, NewMemberRequest(ListLoans, "", "alice")))
+	if len(loans) != 1 || loans[0].Renewals != 2 || !loans[0].Due.Equal(now.Add(LoanPeriod)) {
+		t.Errorf("Expected the loan renewed twice but found %v", loans)
+	}
+
+	expectError(t, ask(request, response, &CoolLibraryRequest{"9781617293092", Renew}), "Невалидна заявка")
+	expectError(t, ask(request, response, NewMemberRequest(Renew, "9781617293092", "carol")),
+		"Непознат читател carol")
+}
+
+func TestRenewWithReservations(t *testing.T) {
+	library := NewFancyLibrary(1)
+	library.AddBookJSON([]byte(catalogueBooks[1]))
+	library.AddMember("alice", "Alice")
+	library.AddMember("bob", "Bob")
+
+	request, response := library.Hello()
+	defer close(request)
+
+	ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "alice"))
+	expectError(t, ask(request, response, NewMemberRequest(Reserve, "0954540018", "bob")), "")
+
+	refused := ask(request, response, NewMemberRequest(Renew, "0954540018", "alice"))
+	expectError(t, refused, "Книга 0954540018 е запазена от други читатели")
+	if _, err := refused.GetBook(); !errors.Is(err, ErrReservedByOthers) {
+		t.Errorf("Expected ErrReservedByOthers but found %v", err)
+	}
+	loans := loansOf(t, ask(request, response, NewMemberRequest(ListLoans, "", "alice")))
+	if len(loans) != 1 || loans[0].Renewals != 0 {
+		t.Errorf("Expected the loan without renewals but found %v", loans)
+	}
+
+	expectError(t, ask(request, response, NewMemberRequest(CancelReservation, "0954540018", "bob")), "")
+	expectError(t, ask(request, response, NewMemberRequest(Renew, "0954540018", "alice")), "")
+}
+
+func TestRenewalsAreStored(t *testing.T) {
+	storage := NewMemoryStorage()
+	library, _ := OpenLibrary(1, storage)
+	library.AddBookJSON([]byte(catalogueBooks[1]))
+	library.AddMember("alice", "Alice")
+
+	request, response := library.Hello()
+	ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "alice"))
+	renewed := ask(request, response, NewMemberRequest(Renew, "0954540018", "alice"))
+	close(request)
+
+	reopened, _ := OpenLibrary(1, storage)
+	request, response = reopened.Hello()
+	defer close(request)
+	loans := loansOf(t, ask(request, response, NewMemberRequest(ListLoans, "", "alice")))
+	due := renewed.(LibraryLoanResponse).GetDueDate()
+	if len(loans) != 1 || loans[0].Renewals != 1 || !loans[0].Due.Equal(due) {
+		t.Errorf("Expected the renewed loan due %s but found %v", due, loans)
+	}
+}
@@ -0,0 +1,253 @@
+package librarian
+
+import "time"
+
+// Types of the requests about reservations, all of them are made by members
+const (
+	// waits for the book, the next free copy is held for the member
+	Reserve = iota + ListOverdue + 1
+	// stops waiting for the book
+	CancelReservation
+	// the place of the member in the queue for the book
+	QueuePosition
+)
+
+// How long a returned copy is held for the next member in the queue
+const DefaultHoldWindow = 3 * 24 * time.Hour
+
+// Reservation - a member waiting for a copy of a book
+type Reservation struct {
+	MemberID string
+	ISBN     string
+	Reserved time.Time
+	// when the copy held for the member is released, zero while the member waits
+	HeldUntil time.Time
+}
+
+// Sets how long a returned copy is held for the next member in the queue
+func WithHoldWindow(window time.Duration) Option {
+	return func(library *FancyLibrary) {
+		library.holdWindow = window
+	}
+}
+
+// The type for the responses about reservations
+type CoolReservationResponse struct {
+	CoolLibraryResponse
+	position  int
+	heldUntil time.Time
+}
+
+// Returns the place of the member in the queue, starting from 1
+func (response *CoolReservationResponse) GetPosition() int {
+	return response.position
+}
+
+// Returns until when a copy is held for the member, zero if the member still waits
+func (response *CoolReservationResponse) GetHeldUntil() time.Time {
+	return response.heldUntil
+}
+
+// Releases the expired holds and holds the available copies for the members in the queue
+// Has to be called while holding the mutex
+func (library *FancyLibrary) refreshHolds(book *Book) {
+	now := library.now()
+	queue := library.holds[book.ISBN]
+	// the held reservations are always in front of the waiting ones
+	for len(queue) > 0 && !queue[0].HeldUntil.IsZero() && now.After(queue[0].HeldUntil) {
+		queue = queue[1:]
+		book.held -= 1
+		book.availableCount += 1
+		library.removeReturned(book)
+	}
+	for _, reservation := range queue {
+		if book.availableCount <= 0 {
+			break
+		}
+		if reservation.HeldUntil.IsZero() {
+			reservation.HeldUntil = now.Add(library.holdWindow)
+			book.availableCount -= 1
+			book.held += 1
+		}
+	}
+	if _, ok := library.books[book.ISBN]; ok {
+		library.setQueue(book.ISBN, queue)
+	}
+}
+
+// Returns if refreshHolds would change the book - a held copy has expired
+// or there is an available copy for a waiting member
+// Has to be called while holding the mutex
+func (library *FancyLibrary) staleHolds(book *Book) bool {
+	queue := library.holds[book.ISBN]
+	if len(queue) > 0 && !queue[0].HeldUntil.IsZero() && library.now().After(queue[0].HeldUntil) {
+		return true
+	}
+	for _, reservation := range queue {
+		if book.availableCount > 0 && reservation.HeldUntil.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// Refreshes the holds of the books outside of other changes.
+// The books whose holds change are saved in the storage, nothing changes if they can not be saved
+// Has to be called while holding the mutex
+func (library *FancyLibrary) saveHolds(books ...*Book) error {
+	stale := make([]*Book, 0)
+	for _, book := range books {
+		if library.staleHolds(book) {
+			stale = append(stale, book)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	return library.transaction(func(saved *snapshot) error {
+		for _, book := range stale {
+			saved.save(library, book.ISBN, "")
+			library.refreshHolds(book)
+		}
+		return nil
+	})
+}
+
+// Refreshes the holds of all the books and saves the changed ones
+// Has to be called while holding the mutex
+func (library *FancyLibrary) refreshAllHolds() error {
+	books := make([]*Book, 0, len(library.holds))
+	for isbn := range library.holds {
+		if book, ok := library.books[isbn]; ok {
+			books = append(books, book)
+		}
+	}
+	return library.saveHolds(books...)
+}
+
+// Returns the place of the reservation of the member in the queue for the book, -1 if there is none
+// Has to be called while holding the mutex
+func (library *FancyLibrary) queueIndex(isbn string, memberID string) int {
+	for i, reservation := range library.holds[isbn] {
+		if reservation.MemberID == memberID {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns if a copy of the book is held for the member
+// Has to be called while holding the mutex
+func (library *FancyLibrary) holdsCopy(isbn string, memberID string) bool {
+	index := library.queueIndex(isbn, memberID)
+	return index >= 0 && !library.holds[isbn][index].HeldUntil.IsZero()
+}
+
+// Removes the reservation of the member from the queue for the book.
+// Returns if a copy was held for the member - the copy stays held
+// Has to be called while holding the mutex
+func (library *FancyLibrary) dropReservation(isbn string, memberID string) (found bool, held bool) {
+	index := library.queueIndex(isbn, memberID)
+	if index < 0 {
+		return false, false
+	}
+	queue := library.holds[isbn]
+	held = !queue[index].HeldUntil.IsZero()
+	library.setQueue(isbn, append(queue[:index:index], queue[index+1:]...))
+	return true, held
+}
+
+// Has to be called while holding the mutex
+func (library *FancyLibrary) setQueue(isbn string, queue []*Reservation) {
+	if len(queue) == 0 {
+		delete(library.holds, isbn)
+	} else {
+		library.holds[isbn] = queue
+	}
+}
+
+// Returns the book and the member of a reservation request
+// Has to be called while holding the mutex
+func (library *FancyLibrary) reservationOf(isbn string, memberID string) (*Book, *Member, error) {
+	member, err := library.member(memberID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if member == nil {
+		return nil, nil, library.fail(LibraryError{Kind: ErrInvalidRequest})
+	}
+	book, ok := library.books[isbn]
+	if !ok {
+		return nil, nil, library.fail(LibraryError{Kind: ErrUnknownBook, ISBN: isbn})
+	}
+	if err := library.saveHolds(book); err != nil {
+		return nil, nil, err
+	}
+	// an expired hold can remove the last copy of a withdrawn book
+	if book, ok = library.books[isbn]; !ok {
+		return nil, nil, library.fail(LibraryError{Kind: ErrUnknownBook, ISBN: isbn})
+	}
+	return book, member, nil
+}
+
+// Returns the response with the place of the member in the queue
+// Has to be called while holding the mutex
+func (library *FancyLibrary) position(book *Book, member *Member) *CoolReservationResponse {
+	index := library.queueIndex(book.ISBN, member.ID)
+	if index < 0 {
+		return &CoolReservationResponse{CoolLibraryResponse: CoolLibraryResponse{book: book,
+			err: library.fail(LibraryError{Kind: ErrNotReserved, ISBN: book.ISBN, MemberID: member.ID})}}
+	}
+	reservation := library.holds[book.ISBN][index]
+	return &CoolReservationResponse{CoolLibraryResponse: CoolLibraryResponse{book: book},
+		position: index + 1, heldUntil: reservation.HeldUntil}
+}
+
+// Answers the requests about reservations
+func (librarian *Librarian) reservation(requestType int, isbn string, memberID string) LibraryResponse {
+	library := librarian.library
+	library.mutex.Lock()
+	defer library.mutex.Unlock()
+	isbn = library.resolve(isbn)
+	book, member, err := library.reservationOf(isbn, memberID)
+	if err != nil {
+		return detach(&CoolReservationResponse{CoolLibraryResponse: CoolLibraryResponse{book: book, err: err}})
+	}
+	if requestType == QueuePosition {
+		return detach(library.position(book, member))
+	}
+	return detach(library.commit(isbn, member.ID, func() LibraryResponse {
+		return library.reserve(requestType, book, member)
+	}))
+}
+
+// Reserves a copy of the book for the member or cancels the reservation
+// Has to be called while holding the mutex
+func (library *FancyLibrary) reserve(requestType int, book *Book, member *Member) LibraryResponse {
+	isbn := book.ISBN
+	switch requestType {
+	case Reserve:
+		if library.queueIndex(isbn, member.ID) >= 0 {
+			return &CoolReservationResponse{CoolLibraryResponse: CoolLibraryResponse{book: book,
+				err: library.fail(LibraryError{Kind: ErrAlreadyReserved, ISBN: isbn, MemberID: member.ID})}}
+		}
+		library.holds[isbn] = append(library.holds[isbn],
+			&Reservation{MemberID: member.ID, ISBN: isbn, Reserved: library.now()})
+		library.refreshHolds(book)
+	case CancelReservation:
+		found, held := library.dropReservation(isbn, member.ID)
+		if !found {
+			// the member does not wait for the book
+			return library.position(book, member)
+		}
+		if held {
+			// the copy goes to the next in the queue
+			book.held -= 1
+			book.availableCount += 1
+			library.removeReturned(book)
+			library.refreshHolds(book)
+		}
+		return &CoolReservationResponse{CoolLibraryResponse: CoolLibraryResponse{book: book}}
+	}
+	return library.position(book, member)
+}
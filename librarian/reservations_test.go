@@ -0,0 +1,85 @@
+package librarian
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReservations(t *testing.T) {
+	library := NewFancyLibrary(1, WithHoldWindow(24*time.Hour))
+	start := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	now := start
+	library.now = func() time.Time { return now }
+	library.AddBookJSON([]byte(catalogueBooks[1]))
+	for _, id := range []string{"alice", "bob", "carol"} {
+		library.AddMember(id, id)
+	}
+
+	request, response := library.Hello()
+	defer close(request)
+	expectPosition := func(memberID string, position int, heldUntil time.Time) {
+		t.Helper()
+		message := ask(request, response, NewMemberRequest(QueuePosition, "0954540018", memberID))
+		expectError(t, message, "")
+		reservation := message.(LibraryReservationResponse)
+		if reservation.GetPosition() != position || !reservation.GetHeldUntil().Equal(heldUntil) {
+			t.Errorf("Expected %s to be %d held until %s but found %d held until %s", memberID,
+				position, heldUntil, reservation.GetPosition(), reservation.GetHeldUntil())
+		}
+	}
+	expectAvailability := func(available, registered int) {
+		t.Helper()
+		message := ask(request, response, &CoolLibraryRequest{"0954540018", GetAvailability})
+		if foundAvailable, foundRegistered := message.GetAvailability(); foundAvailable != available ||
+			foundRegistered != registered {
+			t.Errorf("Expected %d of %d copies available but found %d of %d",
+				available, registered, foundAvailable, foundRegistered)
+		}
+	}
+
+	expectError(t, ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "alice")), "")
+	expectError(t, ask(request, response, NewMemberRequest(Reserve, "0954540018", "bob")), "")
+	expectError(t, ask(request, response, NewMemberRequest(Reserve, "0954540018", "carol")), "")
+	expectError(t, ask(request, response, NewMemberRequest(Reserve, "0954540018", "bob")),
+		"Читател bob вече чака книга 0954540018")
+	expectError(t, ask(request, response, NewMemberRequest(Reserve, "0954540018", "dave")),
+		"Непознат читател dave")
+	expectPosition("bob", 1, time.Time{})
+	expectPosition("carol", 2, time.Time{})
+
+	// the returned copy waits for bob
+	now = start.Add(time.Hour)
+	expectError(t, ask(request, response, NewMemberRequest(ReturnBook, "0954540018", "alice")), "")
+	expectPosition("bob", 1, now.Add(24*time.Hour))
+	expectAvailability(0, 1)
+	expectError(t, ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "carol")),
+		"Няма наличност на книга 0954540018")
+	expectError(t, ask(request, response, &CoolLibraryRequest{"0954540018", BorrowBook}),
+		"Няма наличност на книга 0954540018")
+
+	// bob does not come, so it is the turn of carol
+	now = start.Add(26 * time.Hour)
+	expectPosition("carol", 1, now.Add(24*time.Hour))
+	expectError(t, ask(request, response, NewMemberRequest(QueuePosition, "0954540018", "bob")),
+		"Читател bob не чака книга 0954540018")
+
+	// carol does not want it any more, so the copy is free
+	expectError(t, ask(request, response, NewMemberRequest(CancelReservation, "0954540018", "carol")), "")
+	expectAvailability(1, 1)
+	_, err := ask(request, response, NewMemberRequest(CancelReservation, "0954540018", "carol")).GetBook()
+	if !errors.Is(err, ErrNotReserved) {
+		t.Errorf("Expected no reservation of carol but found %v", err)
+	}
+
+	// a reservation of an available copy holds it at once
+	expectError(t, ask(request, response, NewMemberRequest(Reserve, "0954540018", "bob")), "")
+	expectPosition("bob", 1, now.Add(24*time.Hour))
+	expectError(t, ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "alice")),
+		"Няма наличност на книга 0954540018")
+	expectError(t, ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "bob")), "")
+	if len(library.holds) != 0 {
+		t.Errorf("Expected no reservations but found %v", library.holds)
+	}
+	expectAvailability(0, 1)
+}
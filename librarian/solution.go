@@ -1,18 +1,35 @@
 package librarian
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
-	"errors"
 	"fmt"
 	"sync"
+	"time"
 )
 
 // Creates a new library
 func NewLibrary(librarians int) Library {
+	return NewFancyLibrary(librarians)
+}
+
+// Option - configures a FancyLibrary when it is created
+type Option func(*FancyLibrary)
+
+// Creates a new library with all of its features
+func NewFancyLibrary(librarians int, options ...Option) *FancyLibrary {
 	m := make(map[string]*Book)
 	librariansChan := make(chan Librarian, librarians)
-	return &FancyLibrary{books: m, librarians: librariansChan, mutex: sync.Mutex{}}
+	library := &FancyLibrary{books: m, librarians: librariansChan, mutex: sync.Mutex{},
+		members: make(map[string]*Member), now: time.Now,
+		copyLimit: DefaultCopyLimit, copyLimits: make(map[string]int),
+		holds: make(map[string][]*Reservation), holdWindow: DefaultHoldWindow,
+		storage: NewMemoryStorage(), policy: DefaultPolicy}
+	for _, option := range options {
+		option(library)
+	}
+	return library
 }
 
 // Librarian working in the library
@@ -23,54 +40,92 @@ type Librarian struct {
 }
 
 // The librarian waits for requests and helps borrowing a book,
-// returning a book, getting information about a book
+// returning a book, getting information about a book.
+// The librarian is released when the request channel is closed
+// or when no request comes for the idle timeout of the library
 func (librarian *Librarian) serve() {
 	go func() {
+		defer librarian.library.release()
+		idle := librarian.library.idleTimeout
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if idle > 0 {
+			timer = time.NewTimer(idle)
+			defer timer.Stop()
+			timeout = timer.C
+		}
 		for {
-			message, ok := <-librarian.request
-			if ok {
-				isbn := message.GetISBN()
-				requestType := message.GetType()
-				switch requestType {
-				case BorrowBook:
-					libraryResponse := librarian.borrowBook(isbn)
-					librarian.response <- &libraryResponse
-				case ReturnBook:
-					libraryResponse := librarian.returnBook(isbn)
-					librarian.response <- &libraryResponse
-				case GetAvailability:
-					libraryResponse := librarian.getAvailability(isbn)
-					librarian.response <- &libraryResponse
-				default:
-					librarian.response <- &CoolLibraryResponse{book: nil,
-						err: errors.New("Невалидна заявка")}
+			select {
+			case message, ok := <-librarian.request:
+				if !ok {
+					return
+				}
+				librarian.response <- librarian.answer(message)
+				if timer != nil {
+					timer.Reset(idle)
 				}
-			} else {
-				break
+			case <-timeout:
+				go librarian.expire()
+				return
 			}
 		}
 	}()
 }
 
+// Answers the request
+func (librarian *Librarian) answer(message LibraryRequest) LibraryResponse {
+	isbn := message.GetISBN()
+	requestType := message.GetType()
+	switch requestType {
+	case BorrowBook:
+		if _, ok := message.(LibraryMemberRequest); !ok {
+			// the plain requests get the book of the library with its counts as they change
+			response := librarian.borrowBook(isbn)
+			return &response
+		}
+		return librarian.borrow(isbn, memberID(message))
+	case ReturnBook:
+		if _, ok := message.(LibraryMemberRequest); !ok {
+			response := librarian.returnBook(isbn)
+			return &response
+		}
+		return librarian.giveBack(isbn, memberID(message))
+	case GetAvailability:
+		libraryResponse := librarian.getAvailability(isbn)
+		return &libraryResponse
+	case ListBooks, SearchByTitle, SearchByAuthor, ListUnavailable, SearchByGenre:
+		libraryResponse := librarian.searchCatalogue(message)
+		return &libraryResponse
+	case ListLoans, ListOverdue:
+		libraryResponse := librarian.listLoans(memberID(message), requestType == ListOverdue)
+		return &libraryResponse
+	case Reserve, CancelReservation, QueuePosition:
+		return librarian.reservation(requestType, isbn, memberID(message))
+	case Batch:
+		return librarian.batch(message)
+	case Renew:
+		return librarian.renew(isbn, memberID(message))
+	case AddBook:
+		return librarian.add(message)
+	default:
+		return &CoolLibraryResponse{book: nil,
+			err: librarian.library.fail(LibraryError{Kind: ErrInvalidRequest})}
+	}
+}
+
 // Reduces the available count for book with given isbn
 // Returns information about the book
 // Returns error if the book is not in the library or all copies are taken
 func (librarian *Librarian) borrowBook(isbn string) CoolLibraryResponse {
-	librarian.library.mutex.Lock()
-	defer librarian.library.mutex.Unlock()
-	book, ok := librarian.library.books[isbn]
-	var err error = nil
-	if !ok {
-		err = errors.New("Непозната книга " + isbn)
-	} else {
-		if book.availableCount == 0 {
-			err = errors.New("Няма наличност на книга " + isbn)
-		}
-
-		book.availableCount -= 1
-		librarian.library.books[isbn] = book
-	}
-	return CoolLibraryResponse{book: book, err: err}
+	library := librarian.library
+	library.mutex.Lock()
+	defer library.mutex.Unlock()
+	isbn = library.resolve(isbn)
+	response := library.commit(isbn, "", func() LibraryResponse {
+		response := library.lend(isbn, nil)
+		return &response
+	})
+	return *response.(*CoolLibraryResponse)
 }
 
 // Increases the available count for book with given isbn
@@ -78,21 +133,15 @@ func (librarian *Librarian) borrowBook(isbn string) CoolLibraryResponse {
 // Returns error if the book is not in the library
 // or all the books of this type are already in the library
 func (librarian *Librarian) returnBook(isbn string) CoolLibraryResponse {
-	librarian.library.mutex.Lock()
-	book, ok := librarian.library.books[isbn]
-	defer librarian.library.mutex.Unlock()
-	var err error = nil
-	if !ok {
-		err = errors.New("Непозната книга " + isbn)
-	} else {
-		if book.availableCount == book.registeredCount {
-			err = errors.New("Всички копия са налични " + isbn)
-		}
-
-		book.availableCount += 1
-		librarian.library.books[isbn] = book
-	}
-	return CoolLibraryResponse{book: book, err: err}
+	library := librarian.library
+	library.mutex.Lock()
+	defer library.mutex.Unlock()
+	isbn = library.resolve(isbn)
+	response := library.commit(isbn, "", func() LibraryResponse {
+		response := library.takeBack(isbn, nil)
+		return &response
+	})
+	return *response.(*CoolLibraryResponse)
 }
 
 // Returns information about the book
@@ -100,12 +149,17 @@ func (librarian *Librarian) returnBook(isbn string) CoolLibraryResponse {
 func (librarian *Librarian) getAvailability(isbn string) CoolLibraryResponse {
 	librarian.library.mutex.Lock()
 	defer librarian.library.mutex.Unlock()
+	isbn = librarian.library.resolve(isbn)
 	book, ok := librarian.library.books[isbn]
 	var err error = nil
 	if !ok {
-		err = errors.New("Непозната книга " + isbn)
+		err = librarian.library.fail(LibraryError{Kind: ErrUnknownBook, ISBN: isbn})
+	} else {
+		err = librarian.library.saveHolds(book)
 	}
-	return CoolLibraryResponse{book: book, err: err}
+	response := CoolLibraryResponse{book: book, err: err}
+	response.copyBook()
+	return response
 }
 
 type Person struct {
@@ -115,11 +169,22 @@ type Person struct {
 
 // The type for the books that are stored in the library
 type Book struct {
-	ISBN            string `xml:"isbn,attr"`
-	Title           string `xml:"title"`
-	Author          Person `xml:"author"`
+	ISBN    string `xml:"isbn,attr" json:"isbn"`
+	Title   string `xml:"title" json:"title"`
+	Author  Person `xml:"author" json:"author"`
+	Genre   string `xml:"genre" json:"genre,omitempty"`
+	Pages   int    `xml:"pages" json:"pages,omitempty"`
+	Ratings []int  `xml:"ratings>rating" json:"ratings,omitempty"`
+	// the other fields of the book as they were imported - see metadata.go
+	Extras          map[string]interface{} `xml:"-" json:"-"`
 	registeredCount int
 	availableCount  int
+	// the copies taken by members
+	lent int
+	// the copies to be removed when they are returned
+	withdrawn int
+	// the copies in the library kept for reservations, they are not available
+	held int
 }
 
 // Returns a string representation of the book
@@ -133,23 +198,73 @@ type FancyLibrary struct {
 	books      map[string]*Book
 	librarians chan Librarian
 	mutex      sync.Mutex
+	members    map[string]*Member
+	// the clock of the library - replaced in tests
+	now    func() time.Time
+	policy Policy
+	// the most copies of a book, unless the book has its own limit
+	copyLimit  int
+	copyLimits map[string]int
+	// the messages of the errors, the Bulgarian ones if nil
+	messages Messages
+	// how long a librarian waits for a request before it is released, 0 for ever
+	idleTimeout time.Duration
+	// the members waiting for each book, in the order of their reservations
+	holds      map[string][]*Reservation
+	holdWindow time.Duration
+	// every change of the books and the members is saved here
+	storage Storage
 }
 
 // Adds a book to the library
 // Returns the count of all available copies in the library
-// Return an error of the number of copies is more than 4
+// Return an error if the number of copies would be more than the copy limit - 4 by default
+// or if the isbn is not a valid ISBN-10 or ISBN-13
 func (library *FancyLibrary) addBook(book *Book) (int, error) {
-	// Assuming that books with same ISBN are the same
+	count, _, err := library.add(book)
+	return count, err
+}
+
+// Adds a book like addBook and also returns a copy of the book in the library with its counts,
+// nil if the book is not added
+func (library *FancyLibrary) add(book *Book) (int, *Book, error) {
+	isbn, ok := normalizeISBN(book.ISBN)
+	if !ok {
+		return 0, nil, library.fail(LibraryError{Kind: ErrInvalidISBN, ISBN: book.ISBN})
+	}
 	library.mutex.Lock()
 	defer library.mutex.Unlock()
+	// the book is the same in both forms of its isbn
+	book.ISBN = library.resolve(isbn)
+	var count int
+	err := library.transaction(func(saved *snapshot) error {
+		saved.save(library, book.ISBN, "")
+		var err error
+		count, err = library.addCopy(book)
+		return err
+	})
+	if err != nil {
+		return count, nil, err
+	}
+	added := *library.books[book.ISBN]
+	return count, &added, nil
+}
+
+// Adds a copy of the book
+// Has to be called while holding the mutex
+func (library *FancyLibrary) addCopy(book *Book) (int, error) {
+	// Assuming that books with same ISBN are the same
+	limit := library.limitOf(book.ISBN)
 	same_book := library.books[book.ISBN]
 	if same_book != nil {
-		// there are already 4 copies of the book - return error
-		if same_book.registeredCount == 4 {
-			return 4, errors.New("Има 4 копия на книга " + book.ISBN)
+		// there are already enough copies of the book - return error
+		if same_book.registeredCount >= limit {
+			return same_book.registeredCount, library.fail(LibraryError{Kind: ErrCopyLimit, ISBN: book.ISBN, Limit: limit})
 		}
 		same_book.registeredCount += 1
 		same_book.availableCount += 1
+	} else if limit < 1 {
+		return 0, library.fail(LibraryError{Kind: ErrCopyLimit, ISBN: book.ISBN, Limit: limit})
 	} else {
 		book.registeredCount = 1
 		book.availableCount = 1
@@ -160,7 +275,7 @@ func (library *FancyLibrary) addBook(book *Book) (int, error) {
 
 // Add a book from json
 // Returns the count of all available copies in the library
-// Return an error of the number of copies is more than 4
+// Return an error if the number of copies would be more than the copy limit - 4 by default
 func (library *FancyLibrary) AddBookJSON(data []byte) (int, error) {
 	book := new(Book)
 	err := json.Unmarshal(data, book)
@@ -172,7 +287,7 @@ func (library *FancyLibrary) AddBookJSON(data []byte) (int, error) {
 
 // Add a book from xml
 // Returns the count of all available copies in the library
-// Return an error of the number of copies is more than 4
+// Return an error if the number of copies would be more than the copy limit - 4 by default
 func (library *FancyLibrary) AddBookXML(data []byte) (int, error) {
 	book := new(Book)
 	err := xml.Unmarshal(data, book)
@@ -190,13 +305,8 @@ func (library *FancyLibrary) AddBookXML(data []byte) (int, error) {
 // read channel - for receiving results
 // On closing the request (write channel) the librarian is released
 func (library *FancyLibrary) Hello() (chan<- LibraryRequest, <-chan LibraryResponse) {
-	// The librarians can get up to 100 requests before someone reads the response
-	request := make(chan LibraryRequest, 100)
-	response := make(chan LibraryResponse, 100)
-	librarian := Librarian{request: request, response: response, library: library}
-	library.librarians <- librarian
-	librarian.serve()
-	return librarian.request, librarian.response
+	request, response, _ := library.HelloContext(context.Background())
+	return request, response
 }
 
 // Available types of requests
@@ -243,12 +353,30 @@ func (response *CoolLibraryResponse) GetBook() (fmt.Stringer, error) {
 	if response != nil && response.book != nil {
 		return response.book, nil
 	} else {
-		return nil, errors.New("Празен отговор")
+		return nil, &LibraryError{Kind: ErrEmptyResponse}
+	}
+}
+
+// Replaces the book of the response with a copy, so it can be read after the mutex is released
+// Has to be called while holding the mutex
+func (response *CoolLibraryResponse) copyBook() {
+	if response.book != nil {
+		copied := *response.book
+		response.book = &copied
+	}
+}
+
+// Returns the response with a copy of its book, the books of the library change after the request
+// Has to be called while holding the mutex
+func detach(response LibraryResponse) LibraryResponse {
+	if response, ok := response.(interface{ copyBook() }); ok {
+		response.copyBook()
 	}
+	return response
 }
 
 // available - how many books are available after the request is performed
-// registered - how many copies are registered from this book (маx 4).
+// registered - how many copies are registered from this book (at most the copy limit).
 func (response *CoolLibraryResponse) GetAvailability() (available int, registered int) {
 	if response != nil && response.book != nil {
 		return response.book.availableCount, response.book.registeredCount
@@ -0,0 +1,305 @@
+package librarian
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Storage - keeps the books and the members of a library between restarts
+type Storage interface {
+	// Returns all the saved books and members
+	Load() (State, error)
+	// Saves the records of the changed books and members, replacing the older ones.
+	// A book record without a book removes the book
+	Save(changed State) error
+	Close() error
+}
+
+// State - records of books and members as kept by a Storage
+type State struct {
+	Books   []BookRecord   `json:"books,omitempty"`
+	Members []MemberRecord `json:"members,omitempty"`
+}
+
+// BookRecord - a book with its copies and reservations
+type BookRecord struct {
+	ISBN string `json:"isbn"`
+	// nil when the book is not in the library any more
+	Book       *Book         `json:"book,omitempty"`
+	Registered int           `json:"registered"`
+	Available  int           `json:"available"`
+	Lent       int           `json:"lent"`
+	Withdrawn  int           `json:"withdrawn"`
+	Held       int           `json:"held"`
+	Holds      []Reservation `json:"holds,omitempty"`
+}
+
+// LoanRecord - a loan of a member
+type LoanRecord struct {
+	ISBN     string    `json:"isbn"`
+	Borrowed time.Time `json:"borrowed"`
+	Due      time.Time `json:"due"`
+	Renewals int       `json:"renewals,omitempty"`
+}
+
+// MemberRecord - a member with its loans and unpaid fines
+type MemberRecord struct {
+	ID    string       `json:"id"`
+	Name  string       `json:"name"`
+	Loans []LoanRecord `json:"loans,omitempty"`
+	Fines int          `json:"fines,omitempty"`
+}
+
+// MemoryStorage - a Storage which forgets everything when the program stops
+type MemoryStorage struct {
+	mutex   sync.Mutex
+	books   map[string]BookRecord
+	members map[string]MemberRecord
+}
+
+// Creates an empty storage in the memory
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{books: make(map[string]BookRecord), members: make(map[string]MemberRecord)}
+}
+
+// Returns the saved books and members ordered by isbn and id
+func (storage *MemoryStorage) Load() (State, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	state := State{}
+	for _, record := range storage.books {
+		state.Books = append(state.Books, record)
+	}
+	for _, record := range storage.members {
+		state.Members = append(state.Members, record)
+	}
+	sort.Slice(state.Books, func(i, j int) bool {
+		return state.Books[i].ISBN < state.Books[j].ISBN
+	})
+	sort.Slice(state.Members, func(i, j int) bool {
+		return state.Members[i].ID < state.Members[j].ID
+	})
+	return state, nil
+}
+
+// Replaces the records of the changed books and members
+func (storage *MemoryStorage) Save(changed State) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	for _, record := range changed.Books {
+		if record.Book == nil {
+			delete(storage.books, record.ISBN)
+		} else {
+			storage.books[record.ISBN] = record
+		}
+	}
+	for _, record := range changed.Members {
+		storage.members[record.ID] = record
+	}
+	return nil
+}
+
+// There is nothing to close in the memory
+func (storage *MemoryStorage) Close() error {
+	return nil
+}
+
+// Creates a library with the books and members saved in the storage.
+// The changes of the library are saved there too
+func OpenLibrary(librarians int, storage Storage, options ...Option) (*FancyLibrary, error) {
+	state, err := storage.Load()
+	if err != nil {
+		return nil, err
+	}
+	library := NewFancyLibrary(librarians, options...)
+	library.storage = storage
+	library.load(state)
+	return library, nil
+}
+
+// Closes the storage of the library
+func (library *FancyLibrary) Close() error {
+	library.mutex.Lock()
+	defer library.mutex.Unlock()
+	return library.storage.Close()
+}
+
+// Puts the books and the members of the state in the library
+// Has to be called while holding the mutex
+func (library *FancyLibrary) load(state State) {
+	for _, record := range state.Books {
+		if record.Book == nil {
+			continue
+		}
+		book := *record.Book
+		book.registeredCount, book.availableCount = record.Registered, record.Available
+		book.lent, book.withdrawn, book.held = record.Lent, record.Withdrawn, record.Held
+		library.books[book.ISBN] = &book
+		library.setQueue(book.ISBN, reservations(record.Holds))
+	}
+	for _, record := range state.Members {
+		member := &Member{ID: record.ID, Name: record.Name, fines: record.Fines}
+		for _, loan := range record.Loans {
+			if book, ok := library.books[loan.ISBN]; ok {
+				member.loans = append(member.loans,
+					&Loan{Book: book, MemberID: member.ID, Borrowed: loan.Borrowed, Due: loan.Due, Renewals: loan.Renewals})
+			}
+		}
+		library.members[member.ID] = member
+	}
+}
+
+// Returns the record of the book, a record without a book if it is not in the library
+// Has to be called while holding the mutex
+func (library *FancyLibrary) bookRecord(isbn string) BookRecord {
+	book, ok := library.books[isbn]
+	if !ok {
+		return BookRecord{ISBN: isbn}
+	}
+	copied := *book
+	record := BookRecord{ISBN: isbn, Book: &copied, Registered: book.registeredCount,
+		Available: book.availableCount, Lent: book.lent, Withdrawn: book.withdrawn, Held: book.held}
+	for _, reservation := range library.holds[isbn] {
+		record.Holds = append(record.Holds, *reservation)
+	}
+	return record
+}
+
+// Returns the record of the member
+// Has to be called while holding the mutex
+func (library *FancyLibrary) memberRecord(member *Member) MemberRecord {
+	record := MemberRecord{ID: member.ID, Name: member.Name, Fines: member.fines}
+	for _, loan := range member.loans {
+		record.Loans = append(record.Loans,
+			LoanRecord{ISBN: loan.Book.ISBN, Borrowed: loan.Borrowed, Due: loan.Due, Renewals: loan.Renewals})
+	}
+	return record
+}
+
+// Returns new pointers to the reservations
+func reservations(values []Reservation) []*Reservation {
+	queue := make([]*Reservation, len(values))
+	for i := range values {
+		reservation := values[i]
+		queue[i] = &reservation
+	}
+	return queue
+}
+
+// The state of the books and the members before a change,
+// so it can be restored if the change fails
+type snapshot struct {
+	// nil for the books which were not in the library
+	books  map[string]*Book
+	values map[string]Book
+	holds  map[string][]Reservation
+	// nil for the members who were not registered
+	members map[string]*Member
+	loans   map[string][]Loan
+	fines   map[string]int
+}
+
+// Makes the change and saves the changed books and members in the storage.
+// The change has to save each book and member in the snapshot before changing them.
+// Everything is restored if the change fails or can not be stored
+// Has to be called while holding the mutex
+func (library *FancyLibrary) transaction(change func(saved *snapshot) error) error {
+	saved := &snapshot{books: make(map[string]*Book), values: make(map[string]Book),
+		holds: make(map[string][]Reservation), members: make(map[string]*Member), loans: make(map[string][]Loan),
+		fines: make(map[string]int)}
+	err := change(saved)
+	if err == nil {
+		err = library.storage.Save(saved.changed(library))
+	}
+	if err != nil {
+		saved.restore(library)
+	}
+	return err
+}
+
+// Does the request in a transaction for the book and the member.
+// The request fails with the error of the storage if its changes can not be saved
+// Has to be called while holding the mutex
+func (library *FancyLibrary) commit(isbn string, memberID string, request func() LibraryResponse) LibraryResponse {
+	var response LibraryResponse
+	err := library.transaction(func(saved *snapshot) error {
+		saved.save(library, isbn, memberID)
+		response = request()
+		_, err := response.GetBook()
+		return err
+	})
+	if _, requestErr := response.GetBook(); err != nil && requestErr == nil {
+		return &CoolLibraryResponse{err: err}
+	}
+	return response
+}
+
+// Saves the state of the book and the member before a request changes them.
+// Nothing is saved for an empty isbn or member id
+// Has to be called while holding the mutex
+func (saved *snapshot) save(library *FancyLibrary, isbn string, memberID string) {
+	if _, ok := saved.books[isbn]; !ok && isbn != "" {
+		book := library.books[isbn]
+		saved.books[isbn] = book
+		if book != nil {
+			saved.values[isbn] = *book
+		}
+		holds := make([]Reservation, len(library.holds[isbn]))
+		for i, reservation := range library.holds[isbn] {
+			holds[i] = *reservation
+		}
+		saved.holds[isbn] = holds
+	}
+	if _, ok := saved.members[memberID]; !ok && memberID != "" {
+		member := library.members[memberID]
+		saved.members[memberID] = member
+		if member != nil {
+			saved.fines[memberID] = member.fines
+			for _, loan := range member.loans {
+				saved.loans[memberID] = append(saved.loans[memberID], *loan)
+			}
+		}
+	}
+}
+
+// Puts back the saved state
+// Has to be called while holding the mutex
+func (saved *snapshot) restore(library *FancyLibrary) {
+	for isbn, book := range saved.books {
+		if book == nil {
+			delete(library.books, isbn)
+		} else {
+			*book = saved.values[isbn]
+			library.books[isbn] = book
+		}
+		library.setQueue(isbn, reservations(saved.holds[isbn]))
+	}
+	for id, member := range saved.members {
+		if member == nil {
+			delete(library.members, id)
+			continue
+		}
+		member.fines = saved.fines[id]
+		member.loans = nil
+		for i := range saved.loans[id] {
+			loan := saved.loans[id][i]
+			member.loans = append(member.loans, &loan)
+		}
+	}
+}
+
+// Returns the records of the saved books and members as they are now
+// Has to be called while holding the mutex
+func (saved *snapshot) changed(library *FancyLibrary) State {
+	state := State{}
+	for isbn := range saved.books {
+		state.Books = append(state.Books, library.bookRecord(isbn))
+	}
+	for id := range saved.members {
+		if member, ok := library.members[id]; ok {
+			state.Members = append(state.Members, library.memberRecord(member))
+		}
+	}
+	return state
+}
@@ -0,0 +1,224 @@
+package librarian
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Opens a library with the file storage in dir and a fixed clock
+func openFileLibrary(t *testing.T, dir string) (*FancyLibrary, *FileStorage) {
+	t.Helper()
+	storage, err := OpenFileStorage(dir)
+	if err != nil {
+		t.Fatalf("There must not be an error but found %s", err.Error())
+	}
+	library, err := OpenLibrary(1, storage)
+	if err != nil {
+		t.Fatalf("There must not be an error but found %s", err.Error())
+	}
+	library.now = func() time.Time { return time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC) }
+	return library, storage
+}
+
+// Returns the state of the storage as JSON, so states can be compared
+func stateJSON(t *testing.T, storage Storage) string {
+	t.Helper()
+	state, err := storage.Load()
+	if err != nil {
+		t.Fatalf("There must not be an error but found %s", err.Error())
+	}
+	data, _ := json.Marshal(state)
+	return string(data)
+}
+
+// Changes the library in many ways and returns the state of the storage after each change
+func changeLibrary(t *testing.T, library *FancyLibrary, storage Storage) []string {
+	states := []string{stateJSON(t, storage)}
+	library.AddBookJSON([]byte(catalogueBooks[0]))
+	states = append(states, stateJSON(t, storage))
+	library.AddBookJSON([]byte(catalogueBooks[0]))
+	states = append(states, stateJSON(t, storage))
+	library.AddBookJSON([]byte(catalogueBooks[1]))
+	states = append(states, stateJSON(t, storage))
+	library.AddMember("alice", "Alice")
+	states = append(states, stateJSON(t, storage))
+	library.AddMember("bob", "Bob")
+	states = append(states, stateJSON(t, storage))
+
+	request, response := library.Hello()
+	defer close(request)
+	for _, message := range []LibraryRequest{
+		NewMemberRequest(BorrowBook, "9781617293092", "alice"),
+		&CoolLibraryRequest{"9781617293092", BorrowBook},
+		NewMemberRequest(Reserve, "9781617293092", "bob"),
+		NewMemberRequest(BorrowBook, "0954540018", "bob"),
+		NewMemberRequest(ReturnBook, "9781617293092", "alice"),
+	} {
+		expectError(t, ask(request, response, message), "")
+		states = append(states, stateJSON(t, storage))
+	}
+	library.RemoveCopy("0954540018")
+	states = append(states, stateJSON(t, storage))
+	return states
+}
+
+func TestFileStorageRecovery(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "library")
+	defer os.RemoveAll(dir)
+
+	library, storage := openFileLibrary(t, dir)
+	states := changeLibrary(t, library, storage)
+	// the program stops without closing the storage
+	storage.journal.Close()
+
+	library, storage = openFileLibrary(t, dir)
+	if found := stateJSON(t, storage); found != states[len(states)-1] {
+		t.Errorf("Expected\n---\n%s\n---\nbut found\n---\n%s\n---\n", states[len(states)-1], found)
+	}
+	request, response := library.Hello()
+	defer close(request)
+	expectPosition := ask(request, response, NewMemberRequest(QueuePosition, "9781617293092", "bob"))
+	if position := expectPosition.(LibraryReservationResponse).GetPosition(); position != 1 {
+		t.Errorf("Expected bob to be first in the queue but found %d", position)
+	}
+	expectError(t, ask(request, response, NewMemberRequest(ReturnBook, "0954540018", "bob")), "")
+	// the withdrawn copy is gone with its return
+	expectError(t, ask(request, response, &CoolLibraryRequest{"0954540018", GetAvailability}),
+		"Непозната книга 0954540018")
+	expected := stateJSON(t, storage)
+
+	if err := library.Close(); err != nil {
+		t.Fatalf("There must not be an error but found %s", err.Error())
+	}
+	if info, _ := os.Stat(filepath.Join(dir, JournalFile)); info.Size() != 0 {
+		t.Errorf("Expected an empty journal after closing but found %d bytes", info.Size())
+	}
+	_, storage = openFileLibrary(t, dir)
+	defer storage.Close()
+	if found := stateJSON(t, storage); found != expected {
+		t.Errorf("Expected\n---\n%s\n---\nbut found\n---\n%s\n---\n", expected, found)
+	}
+}
+
+func TestTruncatedJournal(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "library")
+	defer os.RemoveAll(dir)
+
+	library, storage := openFileLibrary(t, dir)
+	// some of the changes are in the snapshot
+	library.AddBookJSON([]byte(catalogueBooks[2]))
+	storage.Compact()
+	states := changeLibrary(t, library, storage)
+	storage.journal.Close()
+
+	snapshot, _ := ioutil.ReadFile(filepath.Join(dir, SnapshotFile))
+	journal, _ := ioutil.ReadFile(filepath.Join(dir, JournalFile))
+	for cut := 0; cut <= len(journal); cut++ {
+		crashed, _ := ioutil.TempDir("", "library")
+		ioutil.WriteFile(filepath.Join(crashed, SnapshotFile), snapshot, 0644)
+		ioutil.WriteFile(filepath.Join(crashed, JournalFile), journal[:cut], 0644)
+
+		_, storage := openFileLibrary(t, crashed)
+		complete := 0
+		for _, b := range journal[:cut] {
+			if b == '\n' {
+				complete++
+			}
+		}
+		if found := stateJSON(t, storage); found != states[complete] {
+			t.Errorf("Expected the state after %d changes for a journal cut at %d but found\n---\n%s\n---\n",
+				complete, cut, found)
+		}
+
+		// new changes go after the last complete one
+		storage.Save(State{Members: []MemberRecord{{ID: "carol", Name: "Carol"}}})
+		storage.journal.Close()
+		storage, _ = OpenFileStorage(crashed)
+		state, _ := storage.Load()
+		if last := state.Members[len(state.Members)-1]; last.ID != "carol" {
+			t.Errorf("Expected carol to be saved after a journal cut at %d but found %+v", cut, state.Members)
+		}
+		storage.journal.Close()
+		os.RemoveAll(crashed)
+	}
+
+	// a broken change before the end can not be skipped
+	ioutil.WriteFile(filepath.Join(dir, JournalFile), append([]byte("{broken\n"), journal...), 0644)
+	if _, err := OpenFileStorage(dir); err == nil {
+		t.Errorf("Expected an error for a broken journal")
+	}
+}
+
+// A storage which can not save anything
+type failingStorage struct {
+	*MemoryStorage
+}
+
+func (storage *failingStorage) Save(changed State) error {
+	return errors.New("the disk is full")
+}
+
+func TestStorageFailure(t *testing.T) {
+	library, _ := OpenLibrary(1, &failingStorage{NewMemoryStorage()})
+	if _, err := library.AddBookJSON([]byte(catalogueBooks[0])); err == nil || err.Error() != "the disk is full" {
+		t.Errorf("Expected the error of the storage but found %v", err)
+	}
+	if len(library.books) != 0 {
+		t.Errorf("Expected no books but found %v", library.books)
+	}
+
+	library.storage = NewMemoryStorage()
+	library.AddBookJSON([]byte(catalogueBooks[0]))
+	library.storage = &failingStorage{NewMemoryStorage()}
+	request, response := library.Hello()
+	defer close(request)
+	expectError(t, ask(request, response, &CoolLibraryRequest{"9781617293092", BorrowBook}), "the disk is full")
+	available, registered := ask(request, response, &CoolLibraryRequest{"9781617293092", GetAvailability}).GetAvailability()
+	if available != 1 || registered != 1 {
+		t.Errorf("Expected 1 of 1 copies available but found %d of %d", available, registered)
+	}
+}
+
+func TestExpiredHoldsAreStored(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "library")
+	defer os.RemoveAll(dir)
+	library, storage := openFileLibrary(t, dir)
+	start := library.now()
+	now := start
+	library.now = func() time.Time { return now }
+	library.AddBookJSON([]byte(catalogueBooks[1]))
+	for _, member := range []string{"alice", "bob", "carol"} {
+		library.AddMember(member, member)
+	}
+
+	request, response := library.Hello()
+	defer close(request)
+	ask(request, response, NewMemberRequest(BorrowBook, "0954540018", "alice"))
+	ask(request, response, NewMemberRequest(Reserve, "0954540018", "bob"))
+	ask(request, response, NewMemberRequest(Reserve, "0954540018", "carol"))
+	ask(request, response, NewMemberRequest(ReturnBook, "0954540018", "alice"))
+
+	// only looking at the book releases the hold of bob and holds the copy for carol
+	now = start.Add(DefaultHoldWindow + time.Hour)
+	ask(request, response, &CoolLibraryRequest{"0954540018", GetAvailability})
+	expected := stateJSON(t, storage)
+
+	// the library is not closed, so everything has to be in the journal
+	reopened, err := OpenFileStorage(dir)
+	if err != nil {
+		t.Fatalf("There must not be an error but found %s", err.Error())
+	}
+	defer reopened.Close()
+	if found := stateJSON(t, reopened); found != expected {
+		t.Errorf("Expected\n---\n%s\n---\nbut found\n---\n%s\n---\n", expected, found)
+	}
+	state, _ := reopened.Load()
+	if holds := state.Books[0].Holds; len(holds) != 1 || holds[0].MemberID != "carol" || holds[0].HeldUntil.IsZero() {
+		t.Errorf("Expected the copy held for carol but found %v", holds)
+	}
+}